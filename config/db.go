@@ -4,37 +4,128 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"rental-api/models"
+	"strconv"
+	"time"
 
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-var DB *gorm.DB
+const (
+	DriverSQLite   = "sqlite"
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+)
+
+type DBConfig struct {
+	Driver          string
+	DSN             string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// LoadDBConfig reads the database settings from the environment. DB_DSN takes
+// precedence; otherwise the DSN is built from the DB_* parts for the driver.
+func LoadDBConfig() DBConfig {
+	cfg := DBConfig{
+		Driver:          getEnv("DB_DRIVER", DriverSQLite),
+		DSN:             getEnv("DB_DSN", ""),
+		MaxOpenConns:    getEnvInt("DB_MAX_OPEN_CONNS", 25),
+		MaxIdleConns:    getEnvInt("DB_MAX_IDLE_CONNS", 5),
+		ConnMaxLifetime: getEnvDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+		ConnMaxIdleTime: getEnvDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
+	}
+
+	if cfg.DSN == "" {
+		cfg.DSN = buildDSN(cfg.Driver)
+	}
+	return cfg
+}
+
+func buildDSN(driver string) string {
+	switch driver {
+	case DriverMySQL:
+		return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+			getEnv("DB_USER", "root"),
+			getEnv("DB_PASSWORD", ""),
+			getEnv("DB_HOST", "127.0.0.1"),
+			getEnv("DB_PORT", "3306"),
+			getEnv("DB_NAME", "testdb"),
+		)
+	case DriverPostgres:
+		return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s TimeZone=UTC",
+			getEnv("DB_HOST", "127.0.0.1"),
+			getEnv("DB_PORT", "5432"),
+			getEnv("DB_USER", "postgres"),
+			getEnv("DB_PASSWORD", ""),
+			getEnv("DB_NAME", "testdb"),
+			getEnv("DB_SSLMODE", "disable"),
+		)
+	default:
+		return getEnv("DB_NAME", "rental.db") + "?_busy_timeout=5000&_txlock=immediate&_foreign_keys=on"
+	}
+}
+
+func dialector(cfg DBConfig) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case DriverSQLite:
+		return sqlite.Open(cfg.DSN), nil
+	case DriverMySQL:
+		return mysql.Open(cfg.DSN), nil
+	case DriverPostgres:
+		return postgres.Open(cfg.DSN), nil
+	default:
+		return nil, fmt.Errorf("unsupported DB_DRIVER %q (expected sqlite, mysql or postgres)", cfg.Driver)
+	}
+}
 
-func InitDB() *gorm.DB {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		getEnv("DB_USER", "root"),
-		getEnv("DB_PASSWORD", ""),
-		getEnv("DB_HOST", "127.0.0.1"),
-		getEnv("DB_PORT", "3306"),
-		getEnv("DB_NAME", "testdb"),
-	)
+// InitDB opens the configured database and applies the pool limits. Schema
+// changes are handled by the migrations package.
+func InitDB() (*gorm.DB, error) {
+	cfg := LoadDBConfig()
 
-	var err error
-	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	dial, err := dialector(cfg)
 	if err != nil {
-		log.Fatalf("Failed to connect to the database: %v", err)
+		return nil, err
 	}
 
-	log.Println("Successfully connected to the database.")
+	db, err := gorm.Open(dial, &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the %s database: %w", cfg.Driver, err)
+	}
 
-	if err := autoMigrateModels(); err != nil {
-		log.Fatalf("Auto-migration failed: %v", err)
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
 	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	log.Printf("Successfully connected to the %s database.", cfg.Driver)
 
-	log.Println("Database migrated successfully.")
-	return DB
+	return db, nil
+}
+
+func CloseDB(db *gorm.DB) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Println("Error getting SQL DB instance:", err)
+		return
+	}
+	sqlDB.Close()
+}
+
+// AutoMigrateEnabled reports whether the server should apply pending
+// migrations on startup. It is meant for development only.
+func AutoMigrateEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("DB_AUTO_MIGRATE"))
+	return enabled
 }
 
 func getEnv(key, defaultValue string) string {
@@ -44,17 +135,18 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
-func autoMigrateModels() error {
-	modelsToMigrate := []interface{}{
-		&models.User{},
-		&models.MesinBor{},
-		&models.RentalHistory{},
-		&models.Review{},
-		&models.Maintenance{},
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
 	}
+	return value
+}
 
-	if err := DB.AutoMigrate(modelsToMigrate...); err != nil {
-		return fmt.Errorf("auto-migration failed: %w", err)
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return defaultValue
 	}
-	return nil
+	return value
 }
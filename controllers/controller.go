@@ -1,8 +1,16 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"rental-api/dto"
+	"rental-api/middleware"
 	"rental-api/models"
+	"rental-api/moderation"
+	"rental-api/payments"
+	"rental-api/repository"
 	"rental-api/utils"
 	"strconv"
 	"time"
@@ -10,166 +18,448 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func LogMaintenance(c *gin.Context) {
+type Handler struct {
+	repos        *repository.Repositories
+	gateways     *payments.Registry
+	reviewFilter *moderation.Filter
+}
+
+func NewHandler(repos *repository.Repositories, gateways *payments.Registry, reviewFilter *moderation.Filter) *Handler {
+	return &Handler{repos: repos, gateways: gateways, reviewFilter: reviewFilter}
+}
+
+// authorizeUser allows the owner of a resource, or anyone holding one of the
+// given roles, through. Admins are always allowed.
+func authorizeUser(c *gin.Context, ownerID uint, roles ...string) bool {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		utils.RespondError(c, http.StatusUnauthorized, "Unauthorized: authentication required")
+		return false
+	}
+	if user.ID != ownerID && !user.HasRole(roles...) {
+		utils.RespondError(c, http.StatusForbidden, "Forbidden: you cannot access this resource")
+		return false
+	}
+	return true
+}
+
+// bindOptionalJSON binds the request body into obj when there is one. An
+// empty body leaves obj as it is; the body's length is not checked because
+// chunked requests do not declare one.
+func bindOptionalJSON(c *gin.Context, obj interface{}) error {
+	if err := c.ShouldBindJSON(obj); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+func (h *Handler) LogMaintenance(c *gin.Context) {
 	var maintenance models.Maintenance
 	if err := c.ShouldBindJSON(&maintenance); err != nil {
 		utils.RespondError(c, http.StatusBadRequest, "Invalid data: "+err.Error())
 		return
 	}
 
-	if err := models.CreateMaintenance(&maintenance); err != nil {
+	if err := h.repos.Maintenance.Create(c.Request.Context(), &maintenance); err != nil {
 		utils.RespondError(c, http.StatusInternalServerError, "Failed to log maintenance: "+err.Error())
 		return
 	}
 
-	utils.RespondJSON(c, http.StatusCreated, maintenance)
+	utils.RespondJSON(c, http.StatusCreated, dto.NewMaintenanceResponse(&maintenance))
 }
 
-func GetMaintenance(c *gin.Context) {
+func (h *Handler) GetMaintenance(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.RespondError(c, http.StatusBadRequest, "Invalid ID: "+err.Error())
 		return
 	}
 
-	maintenance, err := models.GetMaintenanceByID(id)
+	maintenance, err := h.repos.Maintenance.GetByID(c.Request.Context(), id)
 	if err != nil {
 		utils.RespondError(c, http.StatusNotFound, "Maintenance record not found")
 		return
 	}
 
-	utils.RespondJSON(c, http.StatusOK, maintenance)
+	utils.RespondJSON(c, http.StatusOK, dto.NewMaintenanceResponse(maintenance))
 }
 
-func ListMaintenance(c *gin.Context) {
-	records, err := models.GetAllMaintenance()
+// ListMaintenance returns a page of maintenance records, filtered by
+// machine_id, fixed and the from/to dates they were logged.
+func (h *Handler) ListMaintenance(c *gin.Context) {
+	params := newListParams(c)
+	filter := repository.MaintenanceFilter{
+		MachineID: params.Uint("machine_id"),
+		Fixed:     params.Bool("fixed"),
+		Created:   params.DateRange("from", "to"),
+	}
+	page := params.Page()
+	if params.err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid query parameter "+params.err.Error())
+		return
+	}
+
+	records, info, err := h.repos.Maintenance.List(c.Request.Context(), filter, page)
 	if err != nil {
-		utils.RespondError(c, http.StatusInternalServerError, "Failed to fetch records: "+err.Error())
+		respondListError(c, err, "Failed to fetch records: ")
 		return
 	}
 
-	utils.RespondJSON(c, http.StatusOK, records)
+	respondPage(c, dto.NewMaintenanceResponses(records), len(records), info)
 }
 
-func CreateRental(c *gin.Context) {
-	var rental models.RentalHistory
-	if err := c.ShouldBindJSON(&rental); err != nil {
+func (h *Handler) CreateRental(c *gin.Context) {
+	user, _ := middleware.CurrentUser(c)
+
+	var input struct {
+		MachineID  uint       `json:"machine_id" binding:"required"`
+		RentalDate time.Time  `json:"rental_date" binding:"required"`
+		DueDate    *time.Time `json:"due_date"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
 		utils.RespondError(c, http.StatusBadRequest, "Invalid rental data: "+err.Error())
 		return
 	}
 
-	if rental.ReturnDate != nil && rental.RentalDate.After(*rental.ReturnDate) {
-		utils.RespondError(c, http.StatusBadRequest, "Return date cannot be before rental date")
+	if input.DueDate != nil && input.RentalDate.After(*input.DueDate) {
+		utils.RespondError(c, http.StatusBadRequest, "Due date cannot be before rental date")
 		return
 	}
 
-	if err := models.CreateRental(&rental); err != nil {
-		utils.RespondError(c, http.StatusInternalServerError, "Failed to create rental: "+err.Error())
+	rental := models.RentalHistory{
+		UserID:     user.ID,
+		MachineID:  input.MachineID,
+		RentalDate: input.RentalDate,
+		DueDate:    input.DueDate,
+	}
+
+	if err := h.repos.Rentals.Create(c.Request.Context(), &rental); err != nil {
+		var conflict *models.BookingConflictError
+		switch {
+		case errors.As(err, &conflict):
+			utils.RespondErrorWithData(c, http.StatusConflict, "Machine is reserved or in maintenance for the rental period", dto.NewBookingConflictResponse(conflict))
+		case errors.Is(err, models.ErrMachineNotFound):
+			utils.RespondError(c, http.StatusNotFound, "Machine not found")
+		case errors.Is(err, models.ErrOutOfStock):
+			utils.RespondError(c, http.StatusConflict, "Machine is out of stock")
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to create rental: "+err.Error())
+		}
 		return
 	}
 
-	utils.RespondJSON(c, http.StatusCreated, rental)
+	utils.RespondJSON(c, http.StatusCreated, dto.NewRentalResponse(&rental))
 }
 
-func GetRental(c *gin.Context) {
+func (h *Handler) GetRental(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.RespondError(c, http.StatusBadRequest, "Invalid rental ID: "+err.Error())
 		return
 	}
 
-	rental, err := models.GetRentalByID(id)
+	rental, err := h.repos.Rentals.GetByID(c.Request.Context(), id)
 	if err != nil {
 		utils.RespondError(c, http.StatusNotFound, "Rental not found")
 		return
 	}
 
-	utils.RespondJSON(c, http.StatusOK, rental)
+	if !authorizeUser(c, rental.UserID, models.RoleStaff) {
+		return
+	}
+
+	events, err := h.repos.Rentals.Events(c.Request.Context(), rental.ID)
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "Failed to fetch rental history: "+err.Error())
+		return
+	}
+
+	extensions, err := h.repos.Rentals.Extensions(c.Request.Context(), rental.ID)
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "Failed to fetch rental extensions: "+err.Error())
+		return
+	}
+
+	inspection, err := h.repos.Rentals.Inspection(c.Request.Context(), rental.ID)
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "Failed to fetch return inspection: "+err.Error())
+		return
+	}
+
+	response := dto.NewRentalResponse(rental)
+	response.StatusHistory = dto.NewRentalStatusEventResponses(events)
+	response.Extensions = dto.NewRentalExtensionResponses(extensions)
+	if inspection != nil {
+		inspectionResponse := dto.NewReturnInspectionResponse(inspection)
+		response.Inspection = &inspectionResponse
+	}
+	utils.RespondJSON(c, http.StatusOK, response)
 }
 
-func ListRentals(c *gin.Context) {
-	rentals, err := models.GetAllRentals()
+// ListRentals returns a page of rentals, filtered by machine_id, user_id,
+// status and the from/to rental dates. Customers only see their own.
+func (h *Handler) ListRentals(c *gin.Context) {
+	user, _ := middleware.CurrentUser(c)
+
+	params := newListParams(c)
+	filter := repository.RentalFilter{
+		MachineID: params.Uint("machine_id"),
+		UserID:    params.Uint("user_id"),
+		Status:    c.Query("status"),
+		Rented:    params.DateRange("from", "to"),
+	}
+	page := params.Page()
+	if params.err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid query parameter "+params.err.Error())
+		return
+	}
+	if !user.HasRole(models.RoleStaff) {
+		filter.UserID = &user.ID
+	}
+
+	rentals, info, err := h.repos.Rentals.List(c.Request.Context(), filter, page)
 	if err != nil {
-		utils.RespondError(c, http.StatusInternalServerError, "Failed to fetch rentals: "+err.Error())
+		respondListError(c, err, "Failed to fetch rentals: ")
 		return
 	}
 
-	utils.RespondJSON(c, http.StatusOK, rentals)
+	respondPage(c, dto.NewRentalResponses(rentals), len(rentals), info)
 }
 
-func ReturnRental(c *gin.Context) {
+func (h *Handler) ReturnRental(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.RespondError(c, http.StatusBadRequest, "Invalid rental ID: "+err.Error())
 		return
 	}
 
-	rental, err := models.GetRentalByID(id)
-	if err != nil {
-		utils.RespondError(c, http.StatusNotFound, "Rental not found")
+	// The inspection is optional. Without an explicit damage charge the
+	// costs of the damage items are charged.
+	var input struct {
+		Condition    string              `json:"condition"`
+		Notes        string              `json:"notes"`
+		DamageItems  []models.DamageItem `json:"damage_items"`
+		DamageCharge *float64            `json:"damage_charge"`
+	}
+	if err := bindOptionalJSON(c, &input); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid inspection data: "+err.Error())
 		return
 	}
 
-	currentTime := time.Now()
-	rental.ReturnDate = &currentTime
+	var inspection *models.ReturnInspection
+	if input.Condition != "" {
+		inspection = &models.ReturnInspection{
+			Condition:   input.Condition,
+			Notes:       input.Notes,
+			DamageItems: input.DamageItems,
+		}
+		if input.DamageCharge != nil {
+			inspection.DamageCharge = *input.DamageCharge
+		} else {
+			inspection.DamageCharge = inspection.DamageItemsTotal()
+		}
+	} else if input.Notes != "" || len(input.DamageItems) > 0 || input.DamageCharge != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Inspection condition is required")
+		return
+	}
 
-	if err := models.MarkAsReturned(id, rental.ReturnDate); err != nil {
-		utils.RespondError(c, http.StatusInternalServerError, "Failed to return rental: "+err.Error())
+	actor, _ := middleware.CurrentUser(c)
+
+	rental, err := h.repos.Rentals.MarkAsReturned(c.Request.Context(), id, time.Now(), &actor.ID, inspection)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidInspection) {
+			utils.RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondTransitionError(c, err, "Failed to return rental: ")
 		return
 	}
 
-	utils.RespondJSON(c, http.StatusOK, gin.H{"message": "Rental returned successfully", "rental": rental})
+	response := dto.NewRentalResponse(rental)
+	if inspection != nil {
+		inspectionResponse := dto.NewReturnInspectionResponse(inspection)
+		response.Inspection = &inspectionResponse
+	}
+	utils.RespondJSON(c, http.StatusOK, gin.H{"message": "Rental returned successfully", "rental": response})
 }
 
-func SubmitReview(c *gin.Context) {
-	var review models.Review
-	if err := c.ShouldBindJSON(&review); err != nil {
+// SubmitReview reviews one of the user's returned rentals. The machine comes
+// from the rental, so reviews are always from people who rented it. Reviews
+// containing blocked terms wait for moderation.
+func (h *Handler) SubmitReview(c *gin.Context) {
+	user, _ := middleware.CurrentUser(c)
+
+	var input struct {
+		RentalID uint   `json:"rental_id" binding:"required"`
+		Rating   int    `json:"rating" binding:"required"`
+		Comment  string `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
 		utils.RespondError(c, http.StatusBadRequest, "Invalid review data: "+err.Error())
 		return
 	}
 
-	if err := models.CreateReview(&review); err != nil {
-		utils.RespondError(c, http.StatusInternalServerError, "Failed to submit review: "+err.Error())
+	review := models.Review{UserID: user.ID, RentalID: &input.RentalID, Rating: input.Rating, Comment: input.Comment}
+	review.Screen(h.reviewFilter.Check(input.Comment))
+	if err := h.repos.Reviews.Create(c.Request.Context(), &review); err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidRating):
+			utils.RespondError(c, http.StatusBadRequest, "Rating must be between 1 and 5")
+		case errors.Is(err, repository.ErrNotFound):
+			utils.RespondError(c, http.StatusNotFound, "Rental not found")
+		case errors.Is(err, models.ErrReviewNotAllowed):
+			utils.RespondError(c, http.StatusForbidden, "You can only review your own returned rentals")
+		case errors.Is(err, models.ErrAlreadyReviewed):
+			utils.RespondError(c, http.StatusConflict, "This rental has already been reviewed")
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to submit review: "+err.Error())
+		}
 		return
 	}
 
-	utils.RespondJSON(c, http.StatusCreated, review)
+	utils.RespondJSON(c, http.StatusCreated, dto.NewReviewResponse(&review))
 }
 
-func GetReview(c *gin.Context) {
+func (h *Handler) GetReview(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.RespondError(c, http.StatusBadRequest, "Invalid review ID: "+err.Error())
 		return
 	}
 
-	review, err := models.GetReviewByID(id)
+	review, err := h.repos.Reviews.GetByID(c.Request.Context(), id)
 	if err != nil {
 		utils.RespondError(c, http.StatusNotFound, "Review not found")
 		return
 	}
 
-	utils.RespondJSON(c, http.StatusOK, review)
+	// Unpublished reviews are only shown to their author and staff.
+	if !review.Published() {
+		user, ok := middleware.CurrentUser(c)
+		if !ok || (user.ID != review.UserID && !user.HasRole(models.RoleStaff)) {
+			utils.RespondError(c, http.StatusNotFound, "Review not found")
+			return
+		}
+	}
+
+	utils.RespondJSON(c, http.StatusOK, dto.NewReviewResponse(review))
+}
+
+// ListReviews returns a page of published reviews, filtered by machine_id,
+// user_id, min_rating, max_rating and the from/to dates they were written.
+func (h *Handler) ListReviews(c *gin.Context) {
+	h.listReviews(c, nil)
+}
+
+func (h *Handler) listReviews(c *gin.Context, scope func(filter *repository.ReviewFilter)) {
+	params := newListParams(c)
+	filter := repository.ReviewFilter{
+		MachineID: params.Uint("machine_id"),
+		UserID:    params.Uint("user_id"),
+		MinRating: params.Int("min_rating"),
+		MaxRating: params.Int("max_rating"),
+		Created:   params.DateRange("from", "to"),
+	}
+	page := params.Page()
+	if params.err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid query parameter "+params.err.Error())
+		return
+	}
+	if scope != nil {
+		scope(&filter)
+	}
+
+	reviews, info, err := h.repos.Reviews.List(c.Request.Context(), filter, page)
+	if err != nil {
+		respondListError(c, err, "Failed to fetch reviews: ")
+		return
+	}
+
+	respondPage(c, dto.NewReviewResponses(reviews), len(reviews), info)
+}
+
+// ListMachineReviews returns a page of a machine's published reviews with the
+// staff replies to them. It takes the same query parameters as ListReviews.
+func (h *Handler) ListMachineReviews(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid machine ID: "+err.Error())
+		return
+	}
+
+	if _, err := h.repos.Machines.GetByID(c.Request.Context(), id); err != nil {
+		utils.RespondError(c, http.StatusNotFound, "Machine not found")
+		return
+	}
+
+	h.listReviews(c, func(filter *repository.ReviewFilter) {
+		machineID := uint(id)
+		filter.MachineID = &machineID
+	})
 }
 
-func ListReviews(c *gin.Context) {
-	reviews, err := models.GetAllReviews()
+// UpdateReview lets the author change their rating and comment. The new text
+// is screened again.
+func (h *Handler) UpdateReview(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		utils.RespondError(c, http.StatusInternalServerError, "Failed to fetch reviews: "+err.Error())
+		utils.RespondError(c, http.StatusBadRequest, "Invalid review ID: "+err.Error())
+		return
+	}
+
+	var input struct {
+		Rating  int    `json:"rating" binding:"required"`
+		Comment string `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid review data: "+err.Error())
+		return
+	}
+
+	review, err := h.repos.Reviews.GetByID(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondError(c, http.StatusNotFound, "Review not found")
+		return
+	}
+
+	if !authorizeUser(c, review.UserID) {
+		return
+	}
+
+	updated := models.Review{Rating: input.Rating, Comment: input.Comment}
+	if err := h.repos.Reviews.Update(c.Request.Context(), id, &updated, h.reviewFilter.Check(input.Comment)); err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidRating):
+			utils.RespondError(c, http.StatusBadRequest, "Rating must be between 1 and 5")
+		case errors.Is(err, repository.ErrNotFound):
+			utils.RespondError(c, http.StatusNotFound, "Review not found")
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to update review: "+err.Error())
+		}
 		return
 	}
 
-	utils.RespondJSON(c, http.StatusOK, reviews)
+	utils.RespondJSON(c, http.StatusOK, dto.NewReviewResponse(&updated))
 }
 
-func DeleteReview(c *gin.Context) {
+func (h *Handler) DeleteReview(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.RespondError(c, http.StatusBadRequest, "Invalid review ID: "+err.Error())
 		return
 	}
 
-	if err := models.DeleteReview(id); err != nil {
+	review, err := h.repos.Reviews.GetByID(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondError(c, http.StatusNotFound, "Review not found")
+		return
+	}
+
+	if !authorizeUser(c, review.UserID, models.RoleStaff) {
+		return
+	}
+
+	if err := h.repos.Reviews.Delete(c.Request.Context(), id); err != nil {
 		utils.RespondError(c, http.StatusInternalServerError, "Failed to delete review: "+err.Error())
 		return
 	}
@@ -177,14 +467,15 @@ func DeleteReview(c *gin.Context) {
 	utils.RespondJSON(c, http.StatusOK, gin.H{"message": "Review deleted successfully"})
 }
 
-func RegisterUser(c *gin.Context) {
+func (h *Handler) RegisterUser(c *gin.Context) {
 	var user models.User
 	if err := c.ShouldBindJSON(&user); err != nil {
 		utils.RespondError(c, http.StatusBadRequest, "Invalid input data: "+err.Error())
 		return
 	}
+	user.Role = models.RoleCustomer
 
-	exists, err := models.CheckUserExists(user.Email)
+	exists, err := h.repos.Users.ExistsByEmail(c.Request.Context(), user.Email)
 	if err != nil {
 		utils.RespondError(c, http.StatusInternalServerError, "Failed to validate user: "+err.Error())
 		return
@@ -194,15 +485,15 @@ func RegisterUser(c *gin.Context) {
 		return
 	}
 
-	if err := models.CreateUser(&user); err != nil {
+	if err := h.repos.Users.Create(c.Request.Context(), &user); err != nil {
 		utils.RespondError(c, http.StatusInternalServerError, "Failed to register user: "+err.Error())
 		return
 	}
 
-	utils.RespondJSON(c, http.StatusCreated, gin.H{"message": "User registered successfully", "user": user})
+	utils.RespondJSON(c, http.StatusCreated, gin.H{"message": "User registered successfully", "user": dto.NewUserResponse(&user)})
 }
 
-func LoginUser(c *gin.Context) {
+func (h *Handler) LoginUser(c *gin.Context) {
 	var loginData struct {
 		Email    string `json:"email" binding:"required"`
 		Password string `json:"password" binding:"required"`
@@ -213,7 +504,7 @@ func LoginUser(c *gin.Context) {
 		return
 	}
 
-	user, err := models.AuthenticateUser(loginData.Email, loginData.Password)
+	user, err := h.repos.Users.Authenticate(c.Request.Context(), loginData.Email, loginData.Password)
 	if err != nil {
 		utils.RespondError(c, http.StatusUnauthorized, "Invalid email or password")
 		return
@@ -228,36 +519,44 @@ func LoginUser(c *gin.Context) {
 	utils.RespondJSON(c, http.StatusOK, gin.H{"message": "Login successful", "token": token})
 }
 
-func GetUser(c *gin.Context) {
+func (h *Handler) GetUser(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.RespondError(c, http.StatusBadRequest, "Invalid user ID: "+err.Error())
 		return
 	}
 
-	user, err := models.GetUserByID(id)
+	if !authorizeUser(c, uint(id)) {
+		return
+	}
+
+	user, err := h.repos.Users.GetByID(c.Request.Context(), id)
 	if err != nil {
 		utils.RespondError(c, http.StatusNotFound, "User not found")
 		return
 	}
 
-	utils.RespondJSON(c, http.StatusOK, user)
+	utils.RespondJSON(c, http.StatusOK, dto.NewUserResponse(user))
 }
 
-func UpdateUser(c *gin.Context) {
+func (h *Handler) UpdateUser(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
+	if !authorizeUser(c, uint(id)) {
+		return
+	}
+
 	var updatedUser models.User
 	if err := c.ShouldBindJSON(&updatedUser); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
 
-	if err := models.UpdateUser(id, &updatedUser); err != nil {
+	if err := h.repos.Users.Update(c.Request.Context(), id, &updatedUser); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -265,14 +564,48 @@ func UpdateUser(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "User updated successfully"})
 }
 
-func DeleteUser(c *gin.Context) {
+func (h *Handler) UpdateUserRole(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid user ID: "+err.Error())
+		return
+	}
+
+	var roleData struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&roleData); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid input data: "+err.Error())
+		return
+	}
+
+	if err := h.repos.Users.UpdateRole(c.Request.Context(), id, roleData.Role); err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidRole):
+			utils.RespondError(c, http.StatusBadRequest, "Invalid role: "+roleData.Role)
+		case errors.Is(err, repository.ErrNotFound):
+			utils.RespondError(c, http.StatusNotFound, "User not found")
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to update role: "+err.Error())
+		}
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, gin.H{"message": "Role updated successfully", "role": roleData.Role})
+}
+
+func (h *Handler) DeleteUser(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	if err := models.DeleteUser(id); err != nil {
+	if !authorizeUser(c, uint(id)) {
+		return
+	}
+
+	if err := h.repos.Users.Delete(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -280,48 +613,50 @@ func DeleteUser(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
 }
 
-func CreateMachine(c *gin.Context) {
+func (h *Handler) CreateMachine(c *gin.Context) {
 	var machine models.MesinBor
 	if err := c.ShouldBindJSON(&machine); err != nil {
 		utils.RespondError(c, http.StatusBadRequest, "Invalid machine data: "+err.Error())
 		return
 	}
 
-	if err := models.CreateMachine(&machine); err != nil {
+	if err := h.repos.Machines.Create(c.Request.Context(), &machine); err != nil {
 		utils.RespondError(c, http.StatusInternalServerError, "Failed to create machine: "+err.Error())
 		return
 	}
 
-	utils.RespondJSON(c, http.StatusCreated, machine)
+	utils.RespondJSON(c, http.StatusCreated, dto.NewMachineResponse(&machine))
 }
 
-func GetMachine(c *gin.Context) {
+func (h *Handler) GetMachine(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.RespondError(c, http.StatusBadRequest, "Invalid machine ID: "+err.Error())
 		return
 	}
 
-	machine, err := models.GetMachineByID(id)
+	machine, err := h.repos.Machines.GetByID(c.Request.Context(), id)
 	if err != nil {
 		utils.RespondError(c, http.StatusNotFound, "Machine not found")
 		return
 	}
 
-	utils.RespondJSON(c, http.StatusOK, machine)
+	utils.RespondJSON(c, http.StatusOK, dto.NewMachineResponse(machine))
 }
 
-func ListMachines(c *gin.Context) {
-	machines, err := models.GetMachines()
+// ListMachines returns the catalog, optionally sorted with ?sort=score (the
+// Bayesian rating score), rating, reviews or name.
+func (h *Handler) ListMachines(c *gin.Context) {
+	machines, err := h.repos.Machines.List(c.Request.Context(), c.Query("sort"))
 	if err != nil {
-		utils.RespondError(c, http.StatusInternalServerError, "Failed to fetch machines: "+err.Error())
+		respondListError(c, err, "Failed to fetch machines: ")
 		return
 	}
 
-	utils.RespondJSON(c, http.StatusOK, machines)
+	utils.RespondJSON(c, http.StatusOK, dto.NewMachineResponses(machines))
 }
 
-func UpdateMachine(c *gin.Context) {
+func (h *Handler) UpdateMachine(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.RespondError(c, http.StatusBadRequest, "Invalid machine ID: "+err.Error())
@@ -334,7 +669,7 @@ func UpdateMachine(c *gin.Context) {
 		return
 	}
 
-	if err := models.UpdateMachine(id, &updatedMachine); err != nil {
+	if err := h.repos.Machines.Update(c.Request.Context(), id, &updatedMachine); err != nil {
 		utils.RespondError(c, http.StatusInternalServerError, "Failed to update machine: "+err.Error())
 		return
 	}
@@ -342,17 +677,65 @@ func UpdateMachine(c *gin.Context) {
 	utils.RespondJSON(c, http.StatusOK, gin.H{"message": "Machine updated successfully"})
 }
 
-func DeleteMachine(c *gin.Context) {
+func (h *Handler) DeleteMachine(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.RespondError(c, http.StatusBadRequest, "Invalid machine ID: "+err.Error())
 		return
 	}
 
-	if err := models.DeleteMachine(id); err != nil {
+	if err := h.repos.Machines.Delete(c.Request.Context(), id); err != nil {
 		utils.RespondError(c, http.StatusInternalServerError, "Failed to delete machine: "+err.Error())
 		return
 	}
 
 	utils.RespondJSON(c, http.StatusOK, gin.H{"message": "Machine deleted successfully"})
 }
+
+const maxAvailabilityDays = 366
+
+func (h *Handler) GetMachineAvailability(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid machine ID: "+err.Error())
+		return
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	from, to := today, today.AddDate(0, 0, 30)
+	if value := c.Query("from"); value != "" {
+		if from, err = time.Parse(models.DateLayout, value); err != nil {
+			utils.RespondError(c, http.StatusBadRequest, "Invalid from date, expected YYYY-MM-DD")
+			return
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		if to, err = time.Parse(models.DateLayout, value); err != nil {
+			utils.RespondError(c, http.StatusBadRequest, "Invalid to date, expected YYYY-MM-DD")
+			return
+		}
+	} else if c.Query("from") != "" {
+		to = from.AddDate(0, 0, 30)
+	}
+
+	if to.Before(from) {
+		utils.RespondError(c, http.StatusBadRequest, "The to date cannot be before the from date")
+		return
+	}
+	if to.Sub(from) >= maxAvailabilityDays*24*time.Hour {
+		utils.RespondError(c, http.StatusBadRequest, fmt.Sprintf("Availability can be requested for at most %d days", maxAvailabilityDays))
+		return
+	}
+
+	availability, err := h.repos.Machines.Availability(c.Request.Context(), id, from, to)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "Machine not found")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "Failed to compute availability: "+err.Error())
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, dto.NewAvailabilityResponse(availability))
+}
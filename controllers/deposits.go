@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"rental-api/dto"
+	"rental-api/middleware"
+	"rental-api/models"
+	"rental-api/repository"
+	"rental-api/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetUserDeposit shows the user's deposit balance and ledger. Users may see
+// their own; staff may see anyone's.
+func (h *Handler) GetUserDeposit(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid user ID: "+err.Error())
+		return
+	}
+
+	if !authorizeUser(c, uint(id), models.RoleStaff) {
+		return
+	}
+
+	if _, err := h.repos.Users.GetByID(c.Request.Context(), id); err != nil {
+		utils.RespondError(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	balance, err := h.repos.Deposits.Balance(c.Request.Context(), uint(id))
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "Failed to fetch deposit balance: "+err.Error())
+		return
+	}
+
+	entries, err := h.repos.Deposits.Entries(c.Request.Context(), uint(id))
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "Failed to fetch deposit entries: "+err.Error())
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, dto.NewDepositResponse(balance, entries))
+}
+
+func (h *Handler) CreditDeposit(c *gin.Context) {
+	h.recordDeposit(c, h.repos.Deposits.Credit, "Deposit credited successfully")
+}
+
+func (h *Handler) RefundDeposit(c *gin.Context) {
+	h.recordDeposit(c, h.repos.Deposits.Refund, "Deposit refunded successfully")
+}
+
+func (h *Handler) recordDeposit(c *gin.Context, record func(ctx context.Context, userID uint, amount float64, reason string, actorID *uint) (*models.DepositEntry, error), message string) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid user ID: "+err.Error())
+		return
+	}
+
+	var input struct {
+		Amount float64 `json:"amount" binding:"required"`
+		Reason string  `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid deposit data: "+err.Error())
+		return
+	}
+
+	actor, _ := middleware.CurrentUser(c)
+
+	entry, err := record(c.Request.Context(), uint(id), input.Amount, input.Reason, &actor.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidDepositAmount):
+			utils.RespondError(c, http.StatusBadRequest, "Amount must be positive")
+		case errors.Is(err, models.ErrInsufficientDeposit):
+			utils.RespondError(c, http.StatusConflict, "Amount exceeds the available deposit")
+		case errors.Is(err, repository.ErrNotFound):
+			utils.RespondError(c, http.StatusNotFound, "User not found")
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to record deposit: "+err.Error())
+		}
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusCreated, gin.H{"message": message, "entry": dto.NewDepositEntryResponse(entry)})
+}
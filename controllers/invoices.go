@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"rental-api/dto"
+	"rental-api/middleware"
+	"rental-api/models"
+	"rental-api/render"
+	"rental-api/repository"
+	"rental-api/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const mimePDF = "application/pdf"
+
+// CreateInvoice issues the invoice of a completed rental or order. Invoices
+// are issued automatically on return, so this mostly returns the existing one
+// with 200; after the invoice was voided it issues a new one and answers 201.
+func (h *Handler) CreateInvoice(c *gin.Context) {
+	var input struct {
+		RentalID *uint `json:"rental_id"`
+		OrderID  *uint `json:"order_id"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid invoice data: "+err.Error())
+		return
+	}
+	if (input.RentalID == nil) == (input.OrderID == nil) {
+		utils.RespondError(c, http.StatusBadRequest, "Set exactly one of rental_id or order_id")
+		return
+	}
+
+	var invoice *models.Invoice
+	var created bool
+	var err error
+	if input.RentalID != nil {
+		invoice, created, err = h.repos.Invoices.GenerateForRental(c.Request.Context(), *input.RentalID)
+	} else {
+		invoice, created, err = h.repos.Invoices.GenerateForOrder(c.Request.Context(), *input.OrderID)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvoiceNotReady):
+			utils.RespondError(c, http.StatusConflict, "Only returned rentals and orders can be invoiced; rentals that are part of an order are invoiced with it")
+		case errors.Is(err, repository.ErrNotFound):
+			utils.RespondError(c, http.StatusNotFound, "Rental or order not found")
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to create invoice: "+err.Error())
+		}
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	utils.RespondJSON(c, status, dto.NewInvoiceResponse(invoice))
+}
+
+// GetInvoice returns the invoice as JSON, or renders it when asked for HTML or
+// PDF with ?format=html|pdf or the Accept header.
+func (h *Handler) GetInvoice(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid invoice ID: "+err.Error())
+		return
+	}
+
+	invoice, err := h.repos.Invoices.GetByID(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondError(c, http.StatusNotFound, "Invoice not found")
+		return
+	}
+
+	if !authorizeUser(c, invoice.UserID, models.RoleStaff) {
+		return
+	}
+
+	format := c.Query("format")
+	if format == "" {
+		switch c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML, mimePDF) {
+		case gin.MIMEHTML:
+			format = "html"
+		case mimePDF:
+			format = "pdf"
+		}
+	}
+
+	switch format {
+	case "", "json":
+		utils.RespondJSON(c, http.StatusOK, dto.NewInvoiceResponse(invoice))
+		return
+	case "html", "pdf":
+	default:
+		utils.RespondError(c, http.StatusBadRequest, "Unsupported format: "+format)
+		return
+	}
+
+	view := render.InvoiceView{Invoice: invoice, Company: render.DefaultCompany()}
+	if customer, err := h.repos.Users.GetByID(c.Request.Context(), int(invoice.UserID)); err == nil {
+		view.Customer = customer
+	}
+
+	var body bytes.Buffer
+	contentType := "text/html; charset=utf-8"
+	if format == "pdf" {
+		contentType = mimePDF
+		err = render.InvoicePDF(&body, view)
+		c.Header("Content-Disposition", `inline; filename="`+invoice.Number+`.pdf"`)
+	} else {
+		err = render.InvoiceHTML(&body, view)
+	}
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "Failed to render invoice: "+err.Error())
+		return
+	}
+
+	c.Data(http.StatusOK, contentType, body.Bytes())
+}
+
+func (h *Handler) ListInvoices(c *gin.Context) {
+	user, _ := middleware.CurrentUser(c)
+
+	var invoices []models.Invoice
+	var err error
+	if user.HasRole(models.RoleStaff) {
+		invoices, err = h.repos.Invoices.List(c.Request.Context())
+	} else {
+		invoices, err = h.repos.Invoices.ListByUser(c.Request.Context(), user.ID)
+	}
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "Failed to fetch invoices: "+err.Error())
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, dto.NewInvoiceResponses(invoices))
+}
+
+func (h *Handler) VoidInvoice(c *gin.Context) {
+	h.updateInvoiceStatus(c, models.InvoiceVoid, "Invoice voided")
+}
+
+func (h *Handler) updateInvoiceStatus(c *gin.Context, status string, message string) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid invoice ID: "+err.Error())
+		return
+	}
+
+	invoice, err := h.repos.Invoices.UpdateStatus(c.Request.Context(), id, status)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			utils.RespondError(c, http.StatusNotFound, "Invoice not found")
+		case errors.Is(err, models.ErrInvalidInvoiceStatus):
+			utils.RespondError(c, http.StatusConflict, "Only issued invoices with no payments can be voided")
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to update invoice: "+err.Error())
+		}
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, gin.H{"message": message, "invoice": dto.NewInvoiceResponse(invoice)})
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"rental-api/dto"
+	"rental-api/models"
+	"rental-api/repository"
+	"rental-api/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) ListOverdueRentals(c *gin.Context) {
+	rentals, err := h.repos.Rentals.ListOverdue(c.Request.Context())
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "Failed to fetch overdue rentals: "+err.Error())
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, dto.NewRentalResponses(rentals))
+}
+
+func (h *Handler) ListLateFeePolicies(c *gin.Context) {
+	policies, err := h.repos.LateFees.List(c.Request.Context())
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "Failed to fetch late fee policies: "+err.Error())
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, dto.NewLateFeePolicyResponses(policies))
+}
+
+// SaveLateFeePolicy creates a policy, replacing any existing policy for the
+// same machine or category.
+func (h *Handler) SaveLateFeePolicy(c *gin.Context) {
+	var input struct {
+		MachineID      *uint    `json:"machine_id"`
+		Category       string   `json:"category"`
+		DailyFee       float64  `json:"daily_fee"`
+		RateMultiplier *float64 `json:"rate_multiplier"`
+		GraceHours     int      `json:"grace_hours"`
+		MaxFee         float64  `json:"max_fee"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid late fee policy data: "+err.Error())
+		return
+	}
+
+	policy := models.DefaultLateFeePolicy()
+	policy.MachineID = input.MachineID
+	if input.Category != "" {
+		policy.Category = &input.Category
+	}
+	policy.DailyFee = input.DailyFee
+	if input.RateMultiplier != nil {
+		policy.RateMultiplier = *input.RateMultiplier
+	}
+	policy.GraceHours = input.GraceHours
+	policy.MaxFee = input.MaxFee
+
+	if err := h.repos.LateFees.Save(c.Request.Context(), &policy); err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidLateFeePolicy):
+			utils.RespondError(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, models.ErrMachineNotFound):
+			utils.RespondError(c, http.StatusNotFound, "Machine not found")
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to save late fee policy: "+err.Error())
+		}
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, dto.NewLateFeePolicyResponse(&policy))
+}
+
+func (h *Handler) DeleteLateFeePolicy(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid policy ID: "+err.Error())
+		return
+	}
+
+	if err := h.repos.LateFees.Delete(c.Request.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.RespondError(c, http.StatusNotFound, "Late fee policy not found")
+			return
+		}
+		utils.RespondError(c, http.StatusInternalServerError, "Failed to delete late fee policy: "+err.Error())
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, gin.H{"message": "Late fee policy deleted successfully"})
+}
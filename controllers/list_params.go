@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"rental-api/dto"
+	"rental-api/models"
+	"rental-api/repository"
+	"rental-api/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listParams reads the filters, sort and paging of a listing from the query
+// string. The first malformed parameter is kept in err.
+type listParams struct {
+	c   *gin.Context
+	err error
+}
+
+func newListParams(c *gin.Context) *listParams {
+	return &listParams{c: c}
+}
+
+func (p *listParams) fail(key string, err error) {
+	if p.err == nil {
+		p.err = fmt.Errorf("%s: %w", key, err)
+	}
+}
+
+// Page reads limit, offset and sort.
+func (p *listParams) Page() repository.Page {
+	page := repository.Page{Sort: p.c.Query("sort")}
+	if limit := p.Int("limit"); limit != nil {
+		page.Limit = *limit
+	}
+	if offset := p.Int("offset"); offset != nil {
+		page.Offset = *offset
+	}
+	return page
+}
+
+func (p *listParams) Int(key string) *int {
+	value := p.c.Query(key)
+	if value == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(value)
+	if err == nil && n < 0 {
+		err = fmt.Errorf("must not be negative")
+	}
+	if err != nil {
+		p.fail(key, err)
+		return nil
+	}
+	return &n
+}
+
+func (p *listParams) Uint(key string) *uint {
+	value := p.c.Query(key)
+	if value == "" {
+		return nil
+	}
+	n, err := strconv.ParseUint(value, 10, 0)
+	if err != nil {
+		p.fail(key, err)
+		return nil
+	}
+	id := uint(n)
+	return &id
+}
+
+func (p *listParams) Bool(key string) *bool {
+	value := p.c.Query(key)
+	if value == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		p.fail(key, err)
+		return nil
+	}
+	return &b
+}
+
+// DateRange reads two YYYY-MM-DD dates. Both days are included.
+func (p *listParams) DateRange(fromKey, toKey string) repository.DateRange {
+	var dates repository.DateRange
+	if value := p.c.Query(fromKey); value != "" {
+		from, err := time.Parse(models.DateLayout, value)
+		if err != nil {
+			p.fail(fromKey, fmt.Errorf("expected YYYY-MM-DD"))
+		} else {
+			dates.From = &from
+		}
+	}
+	if value := p.c.Query(toKey); value != "" {
+		to, err := time.Parse(models.DateLayout, value)
+		if err != nil {
+			p.fail(toKey, fmt.Errorf("expected YYYY-MM-DD"))
+		} else {
+			end := to.AddDate(0, 0, 1)
+			dates.To = &end
+		}
+	}
+	return dates
+}
+
+func respondListError(c *gin.Context, err error, prefix string) {
+	if errors.Is(err, repository.ErrInvalidSort) {
+		utils.RespondError(c, http.StatusBadRequest, "Unsupported sort: "+c.Query("sort"))
+		return
+	}
+	utils.RespondError(c, http.StatusInternalServerError, prefix+err.Error())
+}
+
+// respondPage sends a page of a listing with its paging metadata.
+func respondPage(c *gin.Context, data interface{}, count int, info *repository.PageInfo) {
+	utils.RespondPage(c, http.StatusOK, data, dto.NewPageMeta(info.Total, info.Limit, info.Offset, info.Sort, count))
+}
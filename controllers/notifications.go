@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"net/http"
+	"rental-api/dto"
+	"rental-api/middleware"
+	"rental-api/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListNotifications returns the current user's notifications, newest first.
+// Pass ?unread=true for unread ones only.
+func (h *Handler) ListNotifications(c *gin.Context) {
+	user, _ := middleware.CurrentUser(c)
+
+	notifications, err := h.repos.Notifications.ListByUser(c.Request.Context(), user.ID, c.Query("unread") == "true")
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "Failed to fetch notifications: "+err.Error())
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, dto.NewNotificationResponses(notifications))
+}
+
+func (h *Handler) MarkNotificationRead(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid notification ID: "+err.Error())
+		return
+	}
+
+	user, _ := middleware.CurrentUser(c)
+
+	notification, err := h.repos.Notifications.MarkRead(c.Request.Context(), id, user.ID)
+	if err != nil {
+		utils.RespondError(c, http.StatusNotFound, "Notification not found")
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, dto.NewNotificationResponse(notification))
+}
@@ -0,0 +1,229 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"rental-api/dto"
+	"rental-api/middleware"
+	"rental-api/models"
+	"rental-api/repository"
+	"rental-api/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) CreateOrder(c *gin.Context) {
+	user, _ := middleware.CurrentUser(c)
+
+	var input struct {
+		RentalDate time.Time  `json:"rental_date" binding:"required"`
+		DueDate    *time.Time `json:"due_date"`
+		Notes      string     `json:"notes"`
+		Items      []struct {
+			MachineID uint `json:"machine_id" binding:"required"`
+			Quantity  int  `json:"quantity"`
+		} `json:"items" binding:"required,dive"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid order data: "+err.Error())
+		return
+	}
+
+	if input.DueDate != nil && input.RentalDate.After(*input.DueDate) {
+		utils.RespondError(c, http.StatusBadRequest, "Due date cannot be before rental date")
+		return
+	}
+
+	order := models.RentalOrder{
+		UserID:     user.ID,
+		RentalDate: input.RentalDate,
+		DueDate:    input.DueDate,
+		Notes:      input.Notes,
+	}
+	for _, item := range input.Items {
+		quantity := item.Quantity
+		if quantity == 0 {
+			quantity = 1
+		}
+		order.Lines = append(order.Lines, models.RentalOrderLine{MachineID: item.MachineID, Quantity: quantity})
+	}
+
+	if err := h.repos.Orders.Create(c.Request.Context(), &order); err != nil {
+		respondOrderError(c, err, "Failed to create order: ")
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusCreated, dto.NewOrderResponse(&order))
+}
+
+func (h *Handler) GetOrder(c *gin.Context) {
+	order, ok := h.loadOrder(c)
+	if !ok {
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, dto.NewOrderResponse(order))
+}
+
+func (h *Handler) ListOrders(c *gin.Context) {
+	user, _ := middleware.CurrentUser(c)
+
+	var orders []models.RentalOrder
+	var err error
+	if user.HasRole(models.RoleStaff) {
+		orders, err = h.repos.Orders.List(c.Request.Context())
+	} else {
+		orders, err = h.repos.Orders.ListByUser(c.Request.Context(), user.ID)
+	}
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "Failed to fetch orders: "+err.Error())
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, dto.NewOrderResponses(orders))
+}
+
+func (h *Handler) ConfirmOrder(c *gin.Context) {
+	h.transitionOrder(c, models.RentalConfirmed, "Order confirmed successfully")
+}
+
+func (h *Handler) PickUpOrder(c *gin.Context) {
+	h.transitionOrder(c, models.RentalPickedUp, "Order picked up successfully")
+}
+
+// CancelOrder may be called by the customer as well as by staff.
+func (h *Handler) CancelOrder(c *gin.Context) {
+	if _, ok := h.loadOrder(c); !ok {
+		return
+	}
+	h.transitionOrder(c, models.RentalCancelled, "Order cancelled successfully")
+}
+
+func (h *Handler) ReturnOrder(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid order ID: "+err.Error())
+		return
+	}
+
+	actor, _ := middleware.CurrentUser(c)
+
+	order, err := h.repos.Orders.Return(c.Request.Context(), id, time.Now(), &actor.ID)
+	if err != nil {
+		respondOrderError(c, err, "Failed to return order: ")
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, gin.H{"message": "Order returned successfully", "order": dto.NewOrderResponse(order)})
+}
+
+// ReturnOrderLine returns some or all units of one line of the order. Units
+// that need an inspection can be returned one by one through
+// PUT /rentals/:id/return instead.
+func (h *Handler) ReturnOrderLine(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid order ID: "+err.Error())
+		return
+	}
+	lineID, err := strconv.Atoi(c.Param("line_id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid line ID: "+err.Error())
+		return
+	}
+
+	var input struct {
+		Quantity int `json:"quantity"`
+	}
+	if err := bindOptionalJSON(c, &input); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid input data: "+err.Error())
+		return
+	}
+
+	actor, _ := middleware.CurrentUser(c)
+
+	order, err := h.repos.Orders.ReturnLine(c.Request.Context(), id, uint(lineID), input.Quantity, time.Now(), &actor.ID)
+	if err != nil {
+		respondOrderError(c, err, "Failed to return order line: ")
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, gin.H{"message": "Order line returned successfully", "order": dto.NewOrderResponse(order)})
+}
+
+func (h *Handler) transitionOrder(c *gin.Context, to string, message string) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid order ID: "+err.Error())
+		return
+	}
+
+	var input struct {
+		Note string `json:"note"`
+	}
+	if err := bindOptionalJSON(c, &input); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid input data: "+err.Error())
+		return
+	}
+
+	actor, _ := middleware.CurrentUser(c)
+
+	order, err := h.repos.Orders.Transition(c.Request.Context(), id, to, &actor.ID, input.Note)
+	if err != nil {
+		respondOrderError(c, err, "Failed to update order: ")
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, gin.H{"message": message, "order": dto.NewOrderResponse(order)})
+}
+
+// loadOrder fetches the order named in the path and checks that the current
+// user owns it or is staff.
+func (h *Handler) loadOrder(c *gin.Context) (*models.RentalOrder, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid order ID: "+err.Error())
+		return nil, false
+	}
+
+	order, err := h.repos.Orders.GetByID(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondError(c, http.StatusNotFound, "Order not found")
+		return nil, false
+	}
+
+	if !authorizeUser(c, order.UserID, models.RoleStaff) {
+		return nil, false
+	}
+	return order, true
+}
+
+func respondOrderError(c *gin.Context, err error, prefix string) {
+	var lineErr *models.OrderLineError
+	var invalid *models.InvalidTransitionError
+	var conflict *models.BookingConflictError
+	switch {
+	case errors.As(err, &lineErr) && errors.As(err, &conflict):
+		utils.RespondErrorWithData(c, http.StatusConflict, fmt.Sprintf("Machine %d is reserved or in maintenance for the rental period", lineErr.MachineID), dto.NewBookingConflictResponse(conflict))
+	case errors.As(err, &lineErr) && errors.Is(err, models.ErrOutOfStock):
+		utils.RespondError(c, http.StatusConflict, fmt.Sprintf("Machine %d does not have enough stock", lineErr.MachineID))
+	case errors.As(err, &lineErr) && errors.Is(err, models.ErrMachineNotFound):
+		utils.RespondError(c, http.StatusNotFound, fmt.Sprintf("Machine %d not found", lineErr.MachineID))
+	case errors.Is(err, models.ErrEmptyOrder), errors.Is(err, models.ErrInvalidQuantity),
+		errors.Is(err, models.ErrReturnQuantityTooBig):
+		utils.RespondError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, models.ErrOrderLineNotFound):
+		utils.RespondError(c, http.StatusNotFound, "Order line not found")
+	case errors.Is(err, repository.ErrNotFound):
+		utils.RespondError(c, http.StatusNotFound, "Order not found")
+	case errors.Is(err, models.ErrOrderClosed):
+		utils.RespondError(c, http.StatusConflict, "Order has no open rentals")
+	case errors.As(err, &invalid):
+		utils.RespondError(c, http.StatusConflict, "Invalid status change: "+invalid.Error())
+	default:
+		utils.RespondError(c, http.StatusInternalServerError, prefix+err.Error())
+	}
+}
@@ -0,0 +1,282 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"rental-api/dto"
+	"rental-api/middleware"
+	"rental-api/models"
+	"rental-api/payments"
+	"rental-api/repository"
+	"rental-api/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreatePayment takes a payment against an invoice. Offline methods such as
+// cash only record money staff received, so only staff may use them;
+// customers can pay their own invoices through an online gateway. Amount
+// defaults to the balance due.
+func (h *Handler) CreatePayment(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid invoice ID: "+err.Error())
+		return
+	}
+
+	var input struct {
+		Method    string   `json:"method" binding:"required"`
+		Amount    *float64 `json:"amount"`
+		Reference string   `json:"reference"`
+		Note      string   `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid payment data: "+err.Error())
+		return
+	}
+
+	gateway, err := h.gateways.Get(input.Method)
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Unknown payment method: "+input.Method)
+		return
+	}
+
+	invoice, err := h.repos.Invoices.GetByID(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondError(c, http.StatusNotFound, "Invoice not found")
+		return
+	}
+
+	if !authorizeUser(c, invoice.UserID, models.RoleStaff) {
+		return
+	}
+	actor, _ := middleware.CurrentUser(c)
+	if gateway.Offline() && !actor.HasRole(models.RoleStaff) {
+		utils.RespondError(c, http.StatusForbidden, "Forbidden: only staff can record "+input.Method+" payments")
+		return
+	}
+
+	payment := models.Payment{
+		InvoiceID: invoice.ID,
+		Kind:      models.PaymentKindPayment,
+		Method:    gateway.Name(),
+		Amount:    invoice.BalanceDue(),
+		Reference: input.Reference,
+		Note:      input.Note,
+		ActorID:   &actor.ID,
+	}
+	if input.Amount != nil {
+		payment.Amount = *input.Amount
+	}
+	if err := h.repos.Payments.Create(c.Request.Context(), &payment); err != nil {
+		respondPaymentError(c, err)
+		return
+	}
+
+	result, err := gateway.Charge(c.Request.Context(), payments.ChargeRequest{
+		PaymentID:     payment.ID,
+		InvoiceNumber: invoice.Number,
+		Amount:        payment.Amount,
+		Currency:      payments.Currency,
+		Reference:     payment.Reference,
+	})
+	h.finishPayment(c, &payment, result, err, "Payment recorded successfully")
+}
+
+// RefundPayment pays back part or all of a succeeded payment through the
+// gateway that took it.
+func (h *Handler) RefundPayment(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid invoice ID: "+err.Error())
+		return
+	}
+
+	var input struct {
+		PaymentID uint    `json:"payment_id" binding:"required"`
+		Amount    float64 `json:"amount" binding:"required"`
+		Note      string  `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid refund data: "+err.Error())
+		return
+	}
+
+	actor, _ := middleware.CurrentUser(c)
+	refund := models.Payment{
+		InvoiceID:  uint(id),
+		Kind:       models.PaymentKindRefund,
+		Amount:     input.Amount,
+		Note:       input.Note,
+		RefundOfID: &input.PaymentID,
+		ActorID:    &actor.ID,
+	}
+	if err := h.repos.Payments.Create(c.Request.Context(), &refund); err != nil {
+		respondPaymentError(c, err)
+		return
+	}
+
+	original, err := h.repos.Payments.GetByID(c.Request.Context(), int(input.PaymentID))
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "Failed to fetch payment: "+err.Error())
+		return
+	}
+	gateway, err := h.gateways.Get(original.Method)
+	if err != nil {
+		h.repos.Payments.SetStatus(c.Request.Context(), refund.ID, models.PaymentFailed, "")
+		utils.RespondError(c, http.StatusConflict, "Payment method is no longer available: "+original.Method)
+		return
+	}
+
+	request := payments.RefundRequest{PaymentID: refund.ID, Amount: refund.Amount, Currency: payments.Currency}
+	if original.ExternalID != nil {
+		request.ExternalID = *original.ExternalID
+	}
+	result, err := gateway.Refund(c.Request.Context(), request)
+	h.finishPayment(c, &refund, result, err, "Refund recorded successfully")
+}
+
+// finishPayment stores the gateway's answer for a pending payment. A gateway
+// error fails the payment so it no longer counts against the invoice.
+func (h *Handler) finishPayment(c *gin.Context, payment *models.Payment, result payments.Result, gatewayErr error, message string) {
+	if gatewayErr != nil {
+		h.repos.Payments.SetStatus(c.Request.Context(), payment.ID, models.PaymentFailed, "")
+		utils.RespondError(c, http.StatusBadGateway, "Payment gateway error: "+gatewayErr.Error())
+		return
+	}
+
+	settled, err := h.repos.Payments.SetStatus(c.Request.Context(), payment.ID, result.Status, result.ExternalID)
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "Failed to update payment: "+err.Error())
+		return
+	}
+
+	invoice, err := h.repos.Invoices.GetByID(c.Request.Context(), int(settled.InvoiceID))
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "Failed to fetch invoice: "+err.Error())
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusCreated, gin.H{
+		"message": message,
+		"payment": dto.NewPaymentResponse(settled),
+		"invoice": dto.NewInvoiceResponse(invoice),
+	})
+}
+
+func respondPaymentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrInvalidPaymentAmount):
+		utils.RespondError(c, http.StatusBadRequest, "Amount must be positive")
+	case errors.Is(err, models.ErrOverpayment):
+		utils.RespondError(c, http.StatusConflict, "Amount exceeds the invoice balance, including pending payments")
+	case errors.Is(err, models.ErrRefundTarget):
+		utils.RespondError(c, http.StatusConflict, "Only succeeded payments of this invoice can be refunded")
+	case errors.Is(err, models.ErrRefundTooLarge):
+		utils.RespondError(c, http.StatusConflict, "Amount exceeds what is left of the payment")
+	case errors.Is(err, models.ErrInvoiceVoid):
+		utils.RespondError(c, http.StatusConflict, "Invoice is void")
+	case errors.Is(err, repository.ErrNotFound):
+		utils.RespondError(c, http.StatusNotFound, "Invoice not found")
+	default:
+		utils.RespondError(c, http.StatusInternalServerError, "Failed to record payment: "+err.Error())
+	}
+}
+
+func (h *Handler) ListInvoicePayments(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid invoice ID: "+err.Error())
+		return
+	}
+
+	invoice, err := h.repos.Invoices.GetByID(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondError(c, http.StatusNotFound, "Invoice not found")
+		return
+	}
+
+	if !authorizeUser(c, invoice.UserID, models.RoleStaff) {
+		return
+	}
+
+	records, err := h.repos.Payments.ListByInvoice(c.Request.Context(), invoice.ID)
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "Failed to fetch payments: "+err.Error())
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, dto.NewPaymentResponses(records))
+}
+
+// GetUserBalance shows what the user has been invoiced and paid. Users may see
+// their own; staff may see anyone's.
+func (h *Handler) GetUserBalance(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid user ID: "+err.Error())
+		return
+	}
+
+	if !authorizeUser(c, uint(id), models.RoleStaff) {
+		return
+	}
+
+	if _, err := h.repos.Users.GetByID(c.Request.Context(), id); err != nil {
+		utils.RespondError(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	balance, err := h.repos.Payments.Balance(c.Request.Context(), uint(id))
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "Failed to fetch balance: "+err.Error())
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, dto.NewAccountBalanceResponse(balance))
+}
+
+// PaymentWebhook receives a gateway callback. Redelivered events, and events
+// for payments that are already settled, are acknowledged without changing
+// anything so the gateway stops retrying them.
+func (h *Handler) PaymentWebhook(c *gin.Context) {
+	gateway, err := h.gateways.Get(c.Param("gateway"))
+	if err != nil {
+		utils.RespondError(c, http.StatusNotFound, "Unknown payment gateway")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Failed to read webhook body: "+err.Error())
+		return
+	}
+
+	event, err := gateway.ParseWebhook(c.Request.Header, body)
+	if err != nil {
+		switch {
+		case errors.Is(err, payments.ErrInvalidSignature):
+			utils.RespondError(c, http.StatusUnauthorized, "Invalid webhook signature")
+		case errors.Is(err, payments.ErrWebhooksNotSupported):
+			utils.RespondError(c, http.StatusNotFound, "Gateway does not accept webhooks")
+		default:
+			utils.RespondError(c, http.StatusBadRequest, "Invalid webhook: "+err.Error())
+		}
+		return
+	}
+
+	duplicate, err := h.repos.Payments.ProcessWebhook(c.Request.Context(), event)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			utils.RespondError(c, http.StatusNotFound, "Payment not found")
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to process webhook: "+err.Error())
+		}
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, gin.H{"event_id": event.EventID, "duplicate": duplicate})
+}
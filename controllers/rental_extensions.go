@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"rental-api/dto"
+	"rental-api/middleware"
+	"rental-api/models"
+	"rental-api/repository"
+	"rental-api/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExtendRental pushes back the due date of a rental. The renter may extend
+// their own rental; staff may extend any.
+func (h *Handler) ExtendRental(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid rental ID: "+err.Error())
+		return
+	}
+
+	var input struct {
+		DueDate time.Time `json:"due_date" binding:"required"`
+		Note    string    `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid extension data: "+err.Error())
+		return
+	}
+
+	rental, err := h.repos.Rentals.GetByID(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondError(c, http.StatusNotFound, "Rental not found")
+		return
+	}
+
+	if !authorizeUser(c, rental.UserID, models.RoleStaff) {
+		return
+	}
+
+	actor, _ := middleware.CurrentUser(c)
+
+	rental, extension, err := h.repos.Rentals.Extend(c.Request.Context(), id, input.DueDate, &actor.ID, input.Note)
+	if err != nil {
+		var conflict *models.BookingConflictError
+		switch {
+		case errors.As(err, &conflict):
+			utils.RespondErrorWithData(c, http.StatusConflict, "Machine is already booked for the extra period", dto.NewBookingConflictResponse(conflict))
+		case errors.Is(err, models.ErrExtensionTooShort):
+			utils.RespondError(c, http.StatusBadRequest, "New due date must be after the current due date")
+		case errors.Is(err, models.ErrRentalNotExtendable):
+			utils.RespondError(c, http.StatusConflict, "Rental cannot be extended in its current state")
+		case errors.Is(err, repository.ErrNotFound):
+			utils.RespondError(c, http.StatusNotFound, "Rental not found")
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to extend rental: "+err.Error())
+		}
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, gin.H{
+		"message":   "Rental extended successfully",
+		"rental":    dto.NewRentalResponse(rental),
+		"extension": dto.NewRentalExtensionResponse(extension),
+	})
+}
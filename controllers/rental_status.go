@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"rental-api/dto"
+	"rental-api/middleware"
+	"rental-api/models"
+	"rental-api/repository"
+	"rental-api/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) ConfirmRental(c *gin.Context) {
+	h.transitionRental(c, models.RentalConfirmed, "Rental confirmed successfully")
+}
+
+func (h *Handler) PickUpRental(c *gin.Context) {
+	h.transitionRental(c, models.RentalPickedUp, "Rental picked up successfully")
+}
+
+func (h *Handler) ActivateRental(c *gin.Context) {
+	h.transitionRental(c, models.RentalActive, "Rental activated successfully")
+}
+
+func (h *Handler) MarkRentalOverdue(c *gin.Context) {
+	h.transitionRental(c, models.RentalOverdue, "Rental marked as overdue")
+}
+
+// CancelRental may be called by the renter as well as by staff, so ownership
+// is checked here rather than in the route.
+func (h *Handler) CancelRental(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid rental ID: "+err.Error())
+		return
+	}
+
+	rental, err := h.repos.Rentals.GetByID(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondError(c, http.StatusNotFound, "Rental not found")
+		return
+	}
+
+	if !authorizeUser(c, rental.UserID, models.RoleStaff) {
+		return
+	}
+
+	h.transitionRental(c, models.RentalCancelled, "Rental cancelled successfully")
+}
+
+func (h *Handler) transitionRental(c *gin.Context, to string, message string) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid rental ID: "+err.Error())
+		return
+	}
+
+	var input struct {
+		Note string `json:"note"`
+	}
+	if err := bindOptionalJSON(c, &input); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid input data: "+err.Error())
+		return
+	}
+
+	actor, _ := middleware.CurrentUser(c)
+
+	rental, err := h.repos.Rentals.Transition(c.Request.Context(), id, to, &actor.ID, input.Note)
+	if err != nil {
+		respondTransitionError(c, err, "Failed to update rental: ")
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, gin.H{"message": message, "rental": dto.NewRentalResponse(rental)})
+}
+
+func respondTransitionError(c *gin.Context, err error, prefix string) {
+	var invalid *models.InvalidTransitionError
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		utils.RespondError(c, http.StatusNotFound, "Rental not found")
+	case errors.Is(err, models.ErrAlreadyReturned):
+		utils.RespondError(c, http.StatusConflict, "Rental has already been returned")
+	case errors.As(err, &invalid):
+		utils.RespondError(c, http.StatusConflict, "Invalid status change: "+invalid.Error())
+	default:
+		utils.RespondError(c, http.StatusInternalServerError, prefix+err.Error())
+	}
+}
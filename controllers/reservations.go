@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"rental-api/dto"
+	"rental-api/middleware"
+	"rental-api/models"
+	"rental-api/repository"
+	"rental-api/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) CreateReservation(c *gin.Context) {
+	user, _ := middleware.CurrentUser(c)
+
+	var input struct {
+		MachineID uint      `json:"machine_id" binding:"required"`
+		StartDate time.Time `json:"start_date" binding:"required"`
+		EndDate   time.Time `json:"end_date" binding:"required"`
+		Notes     string    `json:"notes"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid reservation data: "+err.Error())
+		return
+	}
+
+	today := time.Now().Truncate(24 * time.Hour)
+	if input.StartDate.Before(today) {
+		utils.RespondError(c, http.StatusBadRequest, "Start date cannot be in the past")
+		return
+	}
+
+	reservation := models.Reservation{
+		UserID:    user.ID,
+		MachineID: input.MachineID,
+		StartDate: input.StartDate,
+		EndDate:   input.EndDate,
+		Notes:     input.Notes,
+	}
+	if err := h.repos.Reservations.Create(c.Request.Context(), &reservation); err != nil {
+		var conflict *models.BookingConflictError
+		switch {
+		case errors.As(err, &conflict):
+			utils.RespondErrorWithData(c, http.StatusConflict, "Machine is fully booked for the requested period", dto.NewBookingConflictResponse(conflict))
+		case errors.Is(err, models.ErrInvalidBookingRange):
+			utils.RespondError(c, http.StatusBadRequest, "End date must be after start date")
+		case errors.Is(err, models.ErrMachineNotFound):
+			utils.RespondError(c, http.StatusNotFound, "Machine not found")
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to create reservation: "+err.Error())
+		}
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusCreated, dto.NewReservationResponse(&reservation))
+}
+
+func (h *Handler) GetReservation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid reservation ID: "+err.Error())
+		return
+	}
+
+	reservation, err := h.repos.Reservations.GetByID(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondError(c, http.StatusNotFound, "Reservation not found")
+		return
+	}
+
+	if !authorizeUser(c, reservation.UserID, models.RoleStaff) {
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, dto.NewReservationResponse(reservation))
+}
+
+func (h *Handler) ListReservations(c *gin.Context) {
+	user, _ := middleware.CurrentUser(c)
+
+	var reservations []models.Reservation
+	var err error
+	if user.HasRole(models.RoleStaff) {
+		reservations, err = h.repos.Reservations.List(c.Request.Context())
+	} else {
+		reservations, err = h.repos.Reservations.ListByUser(c.Request.Context(), user.ID)
+	}
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "Failed to fetch reservations: "+err.Error())
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, dto.NewReservationResponses(reservations))
+}
+
+func (h *Handler) CancelReservation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid reservation ID: "+err.Error())
+		return
+	}
+
+	reservation, err := h.repos.Reservations.GetByID(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondError(c, http.StatusNotFound, "Reservation not found")
+		return
+	}
+
+	if !authorizeUser(c, reservation.UserID, models.RoleStaff) {
+		return
+	}
+
+	if err := h.repos.Reservations.Cancel(c.Request.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, models.ErrReservationNotOpen):
+			utils.RespondError(c, http.StatusConflict, "Reservation is no longer booked")
+		case errors.Is(err, repository.ErrNotFound):
+			utils.RespondError(c, http.StatusNotFound, "Reservation not found")
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to cancel reservation: "+err.Error())
+		}
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, gin.H{"message": "Reservation cancelled successfully"})
+}
+
+// FulfillReservation starts the rental for a booked reservation. The rental
+// covers the reserved period and the reservation is marked fulfilled.
+func (h *Handler) FulfillReservation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid reservation ID: "+err.Error())
+		return
+	}
+
+	reservation, err := h.repos.Reservations.GetByID(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondError(c, http.StatusNotFound, "Reservation not found")
+		return
+	}
+
+	if !authorizeUser(c, reservation.UserID, models.RoleStaff) {
+		return
+	}
+
+	rental, err := h.repos.Reservations.Fulfill(c.Request.Context(), id)
+	if err != nil {
+		var conflict *models.BookingConflictError
+		switch {
+		case errors.Is(err, models.ErrReservationNotOpen):
+			utils.RespondError(c, http.StatusConflict, "Reservation is no longer booked")
+		case errors.As(err, &conflict):
+			utils.RespondErrorWithData(c, http.StatusConflict, "Machine is reserved or in maintenance for the rental period", dto.NewBookingConflictResponse(conflict))
+		case errors.Is(err, models.ErrOutOfStock):
+			utils.RespondError(c, http.StatusConflict, "Machine is out of stock")
+		case errors.Is(err, models.ErrMachineNotFound), errors.Is(err, repository.ErrNotFound):
+			utils.RespondError(c, http.StatusNotFound, "Reservation or machine not found")
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to start rental: "+err.Error())
+		}
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusCreated, dto.NewRentalResponse(rental))
+}
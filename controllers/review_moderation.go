@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"rental-api/dto"
+	"rental-api/middleware"
+	"rental-api/models"
+	"rental-api/repository"
+	"rental-api/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListPendingReviews returns the moderation queue with each review's open
+// reports.
+func (h *Handler) ListPendingReviews(c *gin.Context) {
+	reviews, err := h.repos.Reviews.ListPending(c.Request.Context())
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, "Failed to fetch reviews: "+err.Error())
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, dto.NewReviewResponses(reviews))
+}
+
+func (h *Handler) ApproveReview(c *gin.Context) {
+	h.moderateReview(c, models.ReviewApproved, "Review approved")
+}
+
+func (h *Handler) RejectReview(c *gin.Context) {
+	h.moderateReview(c, models.ReviewRejected, "Review rejected")
+}
+
+func (h *Handler) moderateReview(c *gin.Context, status string, message string) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid review ID: "+err.Error())
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if err := bindOptionalJSON(c, &input); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid moderation data: "+err.Error())
+		return
+	}
+
+	actor, _ := middleware.CurrentUser(c)
+
+	review, err := h.repos.Reviews.Moderate(c.Request.Context(), id, status, &actor.ID, input.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			utils.RespondError(c, http.StatusNotFound, "Review not found")
+		case errors.Is(err, models.ErrReviewNotPending):
+			utils.RespondError(c, http.StatusConflict, "Only reviews awaiting moderation can be approved or rejected")
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to moderate review: "+err.Error())
+		}
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, gin.H{"message": message, "review": dto.NewReviewResponse(review)})
+}
+
+// ReportReview lets a user flag someone else's published review. The review
+// is hidden until staff look at it again.
+func (h *Handler) ReportReview(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid review ID: "+err.Error())
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid report data: "+err.Error())
+		return
+	}
+
+	user, _ := middleware.CurrentUser(c)
+
+	report := models.ReviewReport{ReviewID: uint(id), ReporterID: user.ID, Reason: input.Reason}
+	if err := h.repos.Reviews.Report(c.Request.Context(), &report); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			utils.RespondError(c, http.StatusNotFound, "Review not found")
+		case errors.Is(err, models.ErrReviewNotReportable):
+			utils.RespondError(c, http.StatusConflict, "You cannot report this review")
+		case errors.Is(err, models.ErrAlreadyReported):
+			utils.RespondError(c, http.StatusConflict, "You have already reported this review")
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to report review: "+err.Error())
+		}
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusCreated, gin.H{"message": "Review reported", "report": dto.NewReviewReportResponse(&report)})
+}
+
+// ReplyToReview posts the shop's public answer to a review, or edits the one
+// already there. The reviewer is notified either way.
+func (h *Handler) ReplyToReview(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid review ID: "+err.Error())
+		return
+	}
+
+	var input struct {
+		Body string `json:"body" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, "Invalid reply data: "+err.Error())
+		return
+	}
+
+	author, _ := middleware.CurrentUser(c)
+
+	reply, err := h.repos.Reviews.SaveReply(c.Request.Context(), id, author.ID, input.Body)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrEmptyReply):
+			utils.RespondError(c, http.StatusBadRequest, "Reply must not be empty")
+		case errors.Is(err, repository.ErrNotFound):
+			utils.RespondError(c, http.StatusNotFound, "Review not found")
+		case errors.Is(err, models.ErrReviewNotPublished):
+			utils.RespondError(c, http.StatusConflict, "Only published reviews can be replied to")
+		default:
+			utils.RespondError(c, http.StatusInternalServerError, "Failed to save reply: "+err.Error())
+		}
+		return
+	}
+
+	utils.RespondJSON(c, http.StatusOK, gin.H{"message": "Reply saved", "reply": dto.NewReviewReplyResponse(reply)})
+}
@@ -0,0 +1,787 @@
+package dto
+
+import (
+	"rental-api/models"
+	"rental-api/pricing"
+	"strconv"
+	"time"
+)
+
+// Response types decouple the API shape from the GORM models. Handlers must
+// never serialize a models type directly.
+
+type UserResponse struct {
+	ID        uint      `json:"id"`
+	Email     string    `json:"email"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type MachineResponse struct {
+	ID                uint           `json:"id"`
+	Name              string         `json:"name"`
+	Category          string         `json:"category"`
+	Description       string         `json:"description"`
+	Brand             string         `json:"brand"`
+	Condition         string         `json:"condition"`
+	StockAvailability int            `json:"stock_availability"`
+	RentalCosts       float64        `json:"rental_costs"`
+	WeeklyRate        float64        `json:"weekly_rate"`
+	MonthlyRate       float64        `json:"monthly_rate"`
+	MinRentalDays     int            `json:"min_rental_days"`
+	DepositAmount     float64        `json:"deposit_amount"`
+	DepositRate       float64        `json:"deposit_rate"`
+	Rating            RatingResponse `json:"rating"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+}
+
+type RatingResponse struct {
+	Average      float64        `json:"average"`
+	Count        int            `json:"count"`
+	Distribution map[string]int `json:"distribution"`
+	Score        float64        `json:"score"`
+}
+
+type RentalResponse struct {
+	ID            uint                        `json:"id"`
+	UserID        uint                        `json:"user_id"`
+	MachineID     uint                        `json:"machine_id"`
+	OrderID       *uint                       `json:"order_id,omitempty"`
+	RentalDate    time.Time                   `json:"rental_date"`
+	DueDate       *time.Time                  `json:"due_date"`
+	ReturnDate    *time.Time                  `json:"return_date"`
+	Status        string                      `json:"status"`
+	TotalCost     float64                     `json:"total_cost"`
+	LateFee       float64                     `json:"late_fee"`
+	OverdueDays   int                         `json:"overdue_days"`
+	DamageCharge  float64                     `json:"damage_charge"`
+	CostBreakdown *pricing.Breakdown          `json:"cost_breakdown,omitempty"`
+	StatusHistory []RentalStatusEventResponse `json:"status_history,omitempty"`
+	Extensions    []RentalExtensionResponse   `json:"extensions,omitempty"`
+	Inspection    *ReturnInspectionResponse   `json:"inspection,omitempty"`
+	CreatedAt     time.Time                   `json:"created_at"`
+	UpdatedAt     time.Time                   `json:"updated_at"`
+}
+
+type RentalStatusEventResponse struct {
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ActorID    *uint     `json:"actor_id"`
+	Note       string    `json:"note,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type RentalExtensionResponse struct {
+	ID              uint      `json:"id"`
+	PreviousDueDate time.Time `json:"previous_due_date"`
+	NewDueDate      time.Time `json:"new_due_date"`
+	PreviousCost    float64   `json:"previous_cost"`
+	AdditionalCost  float64   `json:"additional_cost"`
+	ActorID         *uint     `json:"actor_id"`
+	Note            string    `json:"note,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+type ReturnInspectionResponse struct {
+	ID            uint                `json:"id"`
+	InspectorID   *uint               `json:"inspector_id"`
+	Condition     string              `json:"condition"`
+	Notes         string              `json:"notes"`
+	DamageItems   []models.DamageItem `json:"damage_items"`
+	DamageCharge  float64             `json:"damage_charge"`
+	MaintenanceID *uint               `json:"maintenance_id"`
+	CreatedAt     time.Time           `json:"created_at"`
+}
+
+type ReviewResponse struct {
+	ID               uint                   `json:"id"`
+	UserID           uint                   `json:"user_id"`
+	MachineID        uint                   `json:"machine_id"`
+	RentalID         *uint                  `json:"rental_id,omitempty"`
+	Verified         bool                   `json:"verified"`
+	Rating           int                    `json:"rating"`
+	Comment          string                 `json:"comment"`
+	Status           string                 `json:"status"`
+	ModerationReason string                 `json:"moderation_reason,omitempty"`
+	ModeratedBy      *uint                  `json:"moderated_by,omitempty"`
+	ModeratedAt      *time.Time             `json:"moderated_at,omitempty"`
+	Reports          []ReviewReportResponse `json:"reports,omitempty"`
+	Reply            *ReviewReplyResponse   `json:"reply,omitempty"`
+	CreatedAt        time.Time              `json:"created_at"`
+	UpdatedAt        time.Time              `json:"updated_at"`
+}
+
+type ReviewReplyResponse struct {
+	ID        uint      `json:"id"`
+	ReviewID  uint      `json:"review_id"`
+	AuthorID  uint      `json:"author_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type NotificationResponse struct {
+	ID           uint       `json:"id"`
+	Kind         string     `json:"kind"`
+	Message      string     `json:"message"`
+	ResourceType string     `json:"resource_type,omitempty"`
+	ResourceID   *uint      `json:"resource_id,omitempty"`
+	ReadAt       *time.Time `json:"read_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type ReviewReportResponse struct {
+	ID         uint      `json:"id"`
+	ReviewID   uint      `json:"review_id"`
+	ReporterID uint      `json:"reporter_id"`
+	Reason     string    `json:"reason"`
+	Resolved   bool      `json:"resolved"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type MaintenanceResponse struct {
+	ID        uint       `json:"id"`
+	MachineID uint       `json:"machine_id"`
+	Issue     string     `json:"issue"`
+	Fixed     bool       `json:"fixed"`
+	FixedAt   *time.Time `json:"fixed_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+type ReservationResponse struct {
+	ID        uint      `json:"id"`
+	UserID    uint      `json:"user_id"`
+	MachineID uint      `json:"machine_id"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+	Status    string    `json:"status"`
+	Notes     string    `json:"notes"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type AvailabilityResponse struct {
+	MachineID uint                      `json:"machine_id"`
+	Capacity  int                       `json:"capacity"`
+	From      string                    `json:"from"`
+	To        string                    `json:"to"`
+	Days      []DayAvailabilityResponse `json:"days"`
+}
+
+type DayAvailabilityResponse struct {
+	Date        string `json:"date"`
+	Total       int    `json:"total"`
+	Booked      int    `json:"booked"`
+	Maintenance int    `json:"maintenance"`
+	Available   int    `json:"available"`
+}
+
+type LateFeePolicyResponse struct {
+	ID             uint      `json:"id"`
+	MachineID      *uint     `json:"machine_id"`
+	Category       *string   `json:"category"`
+	DailyFee       float64   `json:"daily_fee"`
+	RateMultiplier float64   `json:"rate_multiplier"`
+	GraceHours     int       `json:"grace_hours"`
+	MaxFee         float64   `json:"max_fee"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type OrderResponse struct {
+	ID         uint                `json:"id"`
+	UserID     uint                `json:"user_id"`
+	RentalDate time.Time           `json:"rental_date"`
+	DueDate    *time.Time          `json:"due_date"`
+	Status     string              `json:"status"`
+	TotalCost  float64             `json:"total_cost"`
+	Notes      string              `json:"notes"`
+	Lines      []OrderLineResponse `json:"lines"`
+	Rentals    []RentalResponse    `json:"rentals,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at"`
+}
+
+type OrderLineResponse struct {
+	ID        uint   `json:"id"`
+	MachineID uint   `json:"machine_id"`
+	Quantity  int    `json:"quantity"`
+	Returned  int    `json:"returned"`
+	RentalIDs []uint `json:"rental_ids,omitempty"`
+}
+
+type InvoiceResponse struct {
+	ID             uint                  `json:"id"`
+	Number         string                `json:"number"`
+	UserID         uint                  `json:"user_id"`
+	RentalID       *uint                 `json:"rental_id,omitempty"`
+	OrderID        *uint                 `json:"order_id,omitempty"`
+	Status         string                `json:"status"`
+	IssueDate      time.Time             `json:"issue_date"`
+	PeriodStart    time.Time             `json:"period_start"`
+	PeriodEnd      time.Time             `json:"period_end"`
+	Lines          []InvoiceLineResponse `json:"lines,omitempty"`
+	Taxes          []InvoiceTaxResponse  `json:"taxes,omitempty"`
+	Subtotal       float64               `json:"subtotal"`
+	TaxTotal       float64               `json:"tax_total"`
+	DepositApplied float64               `json:"deposit_applied"`
+	Total          float64               `json:"total"`
+	AmountPaid     float64               `json:"amount_paid"`
+	BalanceDue     float64               `json:"balance_due"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+}
+
+type InvoiceLineResponse struct {
+	Position    int     `json:"position"`
+	Kind        string  `json:"kind"`
+	Description string  `json:"description"`
+	Quantity    int     `json:"quantity"`
+	UnitPrice   float64 `json:"unit_price"`
+	Amount      float64 `json:"amount"`
+}
+
+type InvoiceTaxResponse struct {
+	Name   string  `json:"name"`
+	Rate   float64 `json:"rate"`
+	Amount float64 `json:"amount"`
+}
+
+type DepositEntryResponse struct {
+	ID        uint      `json:"id"`
+	RentalID  *uint     `json:"rental_id"`
+	Kind      string    `json:"kind"`
+	Amount    float64   `json:"amount"`
+	Reason    string    `json:"reason"`
+	ActorID   *uint     `json:"actor_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type DepositResponse struct {
+	UserID    uint                   `json:"user_id"`
+	Total     float64                `json:"total"`
+	Held      float64                `json:"held"`
+	Available float64                `json:"available"`
+	Entries   []DepositEntryResponse `json:"entries"`
+}
+
+type PaymentResponse struct {
+	ID         uint      `json:"id"`
+	InvoiceID  uint      `json:"invoice_id"`
+	UserID     uint      `json:"user_id"`
+	Kind       string    `json:"kind"`
+	Method     string    `json:"method"`
+	Status     string    `json:"status"`
+	Amount     float64   `json:"amount"`
+	ExternalID *string   `json:"external_id,omitempty"`
+	Reference  string    `json:"reference,omitempty"`
+	Note       string    `json:"note,omitempty"`
+	RefundOfID *uint     `json:"refund_of_id,omitempty"`
+	ActorID    *uint     `json:"actor_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type AccountBalanceResponse struct {
+	UserID      uint    `json:"user_id"`
+	Invoiced    float64 `json:"invoiced"`
+	Paid        float64 `json:"paid"`
+	Refunded    float64 `json:"refunded"`
+	Outstanding float64 `json:"outstanding"`
+}
+
+// PageMeta is the paging metadata sent alongside a page of a listing.
+type PageMeta struct {
+	Total      int64  `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	Sort       string `json:"sort"`
+	HasMore    bool   `json:"has_more"`
+	NextOffset *int   `json:"next_offset"`
+}
+
+// BookingResponse leaves out who holds the booking: conflicts are shown to
+// any customer, and the other bookings may belong to someone else.
+type BookingResponse struct {
+	Type      string     `json:"type"`
+	ID        uint       `json:"id"`
+	StartDate time.Time  `json:"start_date"`
+	EndDate   *time.Time `json:"end_date"`
+}
+
+type BookingConflictResponse struct {
+	Capacity    int               `json:"capacity"`
+	Maintenance int               `json:"maintenance"`
+	Conflicts   []BookingResponse `json:"conflicts"`
+}
+
+func NewUserResponse(user *models.User) UserResponse {
+	return UserResponse{
+		ID:        user.ID,
+		Email:     user.Email,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Role:      user.Role,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+	}
+}
+
+func NewMachineResponse(machine *models.MesinBor) MachineResponse {
+	return MachineResponse{
+		ID:                machine.ID,
+		Name:              machine.Name,
+		Category:          machine.Category,
+		Description:       machine.Description,
+		Brand:             machine.Brand,
+		Condition:         machine.Condition,
+		StockAvailability: machine.StockAvailability,
+		RentalCosts:       machine.RentalCosts,
+		WeeklyRate:        machine.WeeklyRate,
+		MonthlyRate:       machine.MonthlyRate,
+		MinRentalDays:     machine.MinRentalDays,
+		DepositAmount:     machine.DepositAmount,
+		DepositRate:       machine.DepositRate,
+		Rating:            NewRatingResponse(&machine.Ratings),
+		CreatedAt:         machine.CreatedAt,
+		UpdatedAt:         machine.UpdatedAt,
+	}
+}
+
+func NewRatingResponse(summary *models.RatingSummary) RatingResponse {
+	distribution := make(map[string]int, models.MaxRating)
+	for rating, count := range summary.Distribution() {
+		distribution[strconv.Itoa(rating)] = count
+	}
+	return RatingResponse{
+		Average:      summary.Average,
+		Count:        summary.Count,
+		Distribution: distribution,
+		Score:        summary.Score,
+	}
+}
+
+func NewMachineResponses(machines []models.MesinBor) []MachineResponse {
+	responses := make([]MachineResponse, 0, len(machines))
+	for i := range machines {
+		responses = append(responses, NewMachineResponse(&machines[i]))
+	}
+	return responses
+}
+
+func NewRentalResponse(rental *models.RentalHistory) RentalResponse {
+	return RentalResponse{
+		ID:            rental.ID,
+		UserID:        rental.UserID,
+		MachineID:     rental.MachineID,
+		OrderID:       rental.OrderID,
+		RentalDate:    rental.RentalDate,
+		DueDate:       rental.DueDate,
+		ReturnDate:    rental.ReturnDate,
+		Status:        rental.Status,
+		TotalCost:     rental.TotalCost,
+		LateFee:       rental.LateFee,
+		OverdueDays:   rental.OverdueDays,
+		DamageCharge:  rental.DamageCharge,
+		CostBreakdown: rental.CostBreakdown,
+		CreatedAt:     rental.CreatedAt,
+		UpdatedAt:     rental.UpdatedAt,
+	}
+}
+
+func NewRentalResponses(rentals []models.RentalHistory) []RentalResponse {
+	responses := make([]RentalResponse, 0, len(rentals))
+	for i := range rentals {
+		responses = append(responses, NewRentalResponse(&rentals[i]))
+	}
+	return responses
+}
+
+func NewRentalStatusEventResponses(events []models.RentalStatusEvent) []RentalStatusEventResponse {
+	responses := make([]RentalStatusEventResponse, 0, len(events))
+	for _, event := range events {
+		responses = append(responses, RentalStatusEventResponse{
+			FromStatus: event.FromStatus,
+			ToStatus:   event.ToStatus,
+			ActorID:    event.ActorID,
+			Note:       event.Note,
+			CreatedAt:  event.CreatedAt,
+		})
+	}
+	return responses
+}
+
+func NewRentalExtensionResponse(extension *models.RentalExtension) RentalExtensionResponse {
+	return RentalExtensionResponse{
+		ID:              extension.ID,
+		PreviousDueDate: extension.PreviousDueDate,
+		NewDueDate:      extension.NewDueDate,
+		PreviousCost:    extension.PreviousCost,
+		AdditionalCost:  extension.AdditionalCost,
+		ActorID:         extension.ActorID,
+		Note:            extension.Note,
+		CreatedAt:       extension.CreatedAt,
+	}
+}
+
+func NewRentalExtensionResponses(extensions []models.RentalExtension) []RentalExtensionResponse {
+	responses := make([]RentalExtensionResponse, 0, len(extensions))
+	for i := range extensions {
+		responses = append(responses, NewRentalExtensionResponse(&extensions[i]))
+	}
+	return responses
+}
+
+func NewReturnInspectionResponse(inspection *models.ReturnInspection) ReturnInspectionResponse {
+	return ReturnInspectionResponse{
+		ID:            inspection.ID,
+		InspectorID:   inspection.InspectorID,
+		Condition:     inspection.Condition,
+		Notes:         inspection.Notes,
+		DamageItems:   inspection.DamageItems,
+		DamageCharge:  inspection.DamageCharge,
+		MaintenanceID: inspection.MaintenanceID,
+		CreatedAt:     inspection.CreatedAt,
+	}
+}
+
+func NewReviewResponse(review *models.Review) ReviewResponse {
+	response := ReviewResponse{
+		ID:               review.ID,
+		UserID:           review.UserID,
+		MachineID:        review.MachineID,
+		RentalID:         review.RentalID,
+		Verified:         review.Verified(),
+		Rating:           review.Rating,
+		Comment:          review.Comment,
+		Status:           review.Status,
+		ModerationReason: review.ModerationReason,
+		ModeratedBy:      review.ModeratedBy,
+		ModeratedAt:      review.ModeratedAt,
+		CreatedAt:        review.CreatedAt,
+		UpdatedAt:        review.UpdatedAt,
+	}
+	for i := range review.Reports {
+		response.Reports = append(response.Reports, NewReviewReportResponse(&review.Reports[i]))
+	}
+	if review.Reply != nil {
+		reply := NewReviewReplyResponse(review.Reply)
+		response.Reply = &reply
+	}
+	return response
+}
+
+func NewReviewReplyResponse(reply *models.ReviewReply) ReviewReplyResponse {
+	return ReviewReplyResponse{
+		ID:        reply.ID,
+		ReviewID:  reply.ReviewID,
+		AuthorID:  reply.AuthorID,
+		Body:      reply.Body,
+		CreatedAt: reply.CreatedAt,
+		UpdatedAt: reply.UpdatedAt,
+	}
+}
+
+func NewNotificationResponse(notification *models.Notification) NotificationResponse {
+	return NotificationResponse{
+		ID:           notification.ID,
+		Kind:         notification.Kind,
+		Message:      notification.Message,
+		ResourceType: notification.ResourceType,
+		ResourceID:   notification.ResourceID,
+		ReadAt:       notification.ReadAt,
+		CreatedAt:    notification.CreatedAt,
+	}
+}
+
+func NewNotificationResponses(notifications []models.Notification) []NotificationResponse {
+	responses := make([]NotificationResponse, 0, len(notifications))
+	for i := range notifications {
+		responses = append(responses, NewNotificationResponse(&notifications[i]))
+	}
+	return responses
+}
+
+func NewReviewReportResponse(report *models.ReviewReport) ReviewReportResponse {
+	return ReviewReportResponse{
+		ID:         report.ID,
+		ReviewID:   report.ReviewID,
+		ReporterID: report.ReporterID,
+		Reason:     report.Reason,
+		Resolved:   report.Resolved,
+		CreatedAt:  report.CreatedAt,
+	}
+}
+
+func NewReviewResponses(reviews []models.Review) []ReviewResponse {
+	responses := make([]ReviewResponse, 0, len(reviews))
+	for i := range reviews {
+		responses = append(responses, NewReviewResponse(&reviews[i]))
+	}
+	return responses
+}
+
+func NewMaintenanceResponse(maintenance *models.Maintenance) MaintenanceResponse {
+	return MaintenanceResponse{
+		ID:        maintenance.ID,
+		MachineID: maintenance.MachineID,
+		Issue:     maintenance.Issue,
+		Fixed:     maintenance.Fixed,
+		FixedAt:   maintenance.FixedAt,
+		CreatedAt: maintenance.CreatedAt,
+		UpdatedAt: maintenance.UpdatedAt,
+	}
+}
+
+func NewMaintenanceResponses(records []models.Maintenance) []MaintenanceResponse {
+	responses := make([]MaintenanceResponse, 0, len(records))
+	for i := range records {
+		responses = append(responses, NewMaintenanceResponse(&records[i]))
+	}
+	return responses
+}
+
+func NewReservationResponse(reservation *models.Reservation) ReservationResponse {
+	return ReservationResponse{
+		ID:        reservation.ID,
+		UserID:    reservation.UserID,
+		MachineID: reservation.MachineID,
+		StartDate: reservation.StartDate,
+		EndDate:   reservation.EndDate,
+		Status:    reservation.Status,
+		Notes:     reservation.Notes,
+		CreatedAt: reservation.CreatedAt,
+		UpdatedAt: reservation.UpdatedAt,
+	}
+}
+
+func NewReservationResponses(reservations []models.Reservation) []ReservationResponse {
+	responses := make([]ReservationResponse, 0, len(reservations))
+	for i := range reservations {
+		responses = append(responses, NewReservationResponse(&reservations[i]))
+	}
+	return responses
+}
+
+func NewAvailabilityResponse(availability *models.Availability) AvailabilityResponse {
+	days := make([]DayAvailabilityResponse, 0, len(availability.Days))
+	for _, day := range availability.Days {
+		days = append(days, DayAvailabilityResponse{
+			Date:        day.Date,
+			Total:       day.Total,
+			Booked:      day.Booked,
+			Maintenance: day.Maintenance,
+			Available:   day.Available,
+		})
+	}
+	return AvailabilityResponse{
+		MachineID: availability.MachineID,
+		Capacity:  availability.Capacity,
+		From:      availability.From,
+		To:        availability.To,
+		Days:      days,
+	}
+}
+
+func NewLateFeePolicyResponse(policy *models.LateFeePolicy) LateFeePolicyResponse {
+	return LateFeePolicyResponse{
+		ID:             policy.ID,
+		MachineID:      policy.MachineID,
+		Category:       policy.Category,
+		DailyFee:       policy.DailyFee,
+		RateMultiplier: policy.RateMultiplier,
+		GraceHours:     policy.GraceHours,
+		MaxFee:         policy.MaxFee,
+		CreatedAt:      policy.CreatedAt,
+		UpdatedAt:      policy.UpdatedAt,
+	}
+}
+
+func NewLateFeePolicyResponses(policies []models.LateFeePolicy) []LateFeePolicyResponse {
+	responses := make([]LateFeePolicyResponse, 0, len(policies))
+	for i := range policies {
+		responses = append(responses, NewLateFeePolicyResponse(&policies[i]))
+	}
+	return responses
+}
+
+// NewOrderResponse includes the order's rentals when they were loaded; line
+// return counts and rental IDs are derived from them.
+func NewOrderResponse(order *models.RentalOrder) OrderResponse {
+	lines := make([]OrderLineResponse, 0, len(order.Lines))
+	for _, line := range order.Lines {
+		response := OrderLineResponse{ID: line.ID, MachineID: line.MachineID, Quantity: line.Quantity}
+		for _, rental := range order.Rentals {
+			if rental.OrderLineID == nil || *rental.OrderLineID != line.ID {
+				continue
+			}
+			response.RentalIDs = append(response.RentalIDs, rental.ID)
+			if rental.Status == models.RentalReturned {
+				response.Returned++
+			}
+		}
+		lines = append(lines, response)
+	}
+
+	var rentals []RentalResponse
+	if len(order.Rentals) > 0 {
+		rentals = NewRentalResponses(order.Rentals)
+	}
+
+	return OrderResponse{
+		ID:         order.ID,
+		UserID:     order.UserID,
+		RentalDate: order.RentalDate,
+		DueDate:    order.DueDate,
+		Status:     order.Status,
+		TotalCost:  order.TotalCost,
+		Notes:      order.Notes,
+		Lines:      lines,
+		Rentals:    rentals,
+		CreatedAt:  order.CreatedAt,
+		UpdatedAt:  order.UpdatedAt,
+	}
+}
+
+func NewOrderResponses(orders []models.RentalOrder) []OrderResponse {
+	responses := make([]OrderResponse, 0, len(orders))
+	for i := range orders {
+		responses = append(responses, NewOrderResponse(&orders[i]))
+	}
+	return responses
+}
+
+func NewInvoiceResponse(invoice *models.Invoice) InvoiceResponse {
+	response := InvoiceResponse{
+		ID:             invoice.ID,
+		Number:         invoice.Number,
+		UserID:         invoice.UserID,
+		RentalID:       invoice.RentalID,
+		OrderID:        invoice.OrderID,
+		Status:         invoice.Status,
+		IssueDate:      invoice.IssueDate,
+		PeriodStart:    invoice.PeriodStart,
+		PeriodEnd:      invoice.PeriodEnd,
+		Subtotal:       invoice.Subtotal,
+		TaxTotal:       invoice.TaxTotal,
+		DepositApplied: invoice.DepositApplied,
+		Total:          invoice.Total,
+		AmountPaid:     invoice.AmountPaid,
+		BalanceDue:     invoice.BalanceDue(),
+		CreatedAt:      invoice.CreatedAt,
+		UpdatedAt:      invoice.UpdatedAt,
+	}
+	for _, line := range invoice.Lines {
+		response.Lines = append(response.Lines, InvoiceLineResponse{
+			Position:    line.Position,
+			Kind:        line.Kind,
+			Description: line.Description,
+			Quantity:    line.Quantity,
+			UnitPrice:   line.UnitPrice,
+			Amount:      line.Amount,
+		})
+	}
+	for _, tax := range invoice.Taxes {
+		response.Taxes = append(response.Taxes, InvoiceTaxResponse{Name: tax.Name, Rate: tax.Rate, Amount: tax.Amount})
+	}
+	return response
+}
+
+func NewInvoiceResponses(invoices []models.Invoice) []InvoiceResponse {
+	responses := make([]InvoiceResponse, 0, len(invoices))
+	for i := range invoices {
+		responses = append(responses, NewInvoiceResponse(&invoices[i]))
+	}
+	return responses
+}
+
+func NewDepositEntryResponse(entry *models.DepositEntry) DepositEntryResponse {
+	return DepositEntryResponse{
+		ID:        entry.ID,
+		RentalID:  entry.RentalID,
+		Kind:      entry.Kind,
+		Amount:    entry.Amount,
+		Reason:    entry.Reason,
+		ActorID:   entry.ActorID,
+		CreatedAt: entry.CreatedAt,
+	}
+}
+
+func NewDepositResponse(balance *models.DepositBalance, entries []models.DepositEntry) DepositResponse {
+	responses := make([]DepositEntryResponse, 0, len(entries))
+	for i := range entries {
+		responses = append(responses, NewDepositEntryResponse(&entries[i]))
+	}
+	return DepositResponse{
+		UserID:    balance.UserID,
+		Total:     balance.Total,
+		Held:      balance.Held,
+		Available: balance.Available,
+		Entries:   responses,
+	}
+}
+
+func NewPaymentResponse(payment *models.Payment) PaymentResponse {
+	return PaymentResponse{
+		ID:         payment.ID,
+		InvoiceID:  payment.InvoiceID,
+		UserID:     payment.UserID,
+		Kind:       payment.Kind,
+		Method:     payment.Method,
+		Status:     payment.Status,
+		Amount:     payment.Amount,
+		ExternalID: payment.ExternalID,
+		Reference:  payment.Reference,
+		Note:       payment.Note,
+		RefundOfID: payment.RefundOfID,
+		ActorID:    payment.ActorID,
+		CreatedAt:  payment.CreatedAt,
+		UpdatedAt:  payment.UpdatedAt,
+	}
+}
+
+func NewPaymentResponses(payments []models.Payment) []PaymentResponse {
+	responses := make([]PaymentResponse, 0, len(payments))
+	for i := range payments {
+		responses = append(responses, NewPaymentResponse(&payments[i]))
+	}
+	return responses
+}
+
+func NewAccountBalanceResponse(balance *models.AccountBalance) AccountBalanceResponse {
+	return AccountBalanceResponse{
+		UserID:      balance.UserID,
+		Invoiced:    balance.Invoiced,
+		Paid:        balance.Paid,
+		Refunded:    balance.Refunded,
+		Outstanding: balance.Outstanding,
+	}
+}
+
+// NewPageMeta describes a page of count items taken at offset from total.
+func NewPageMeta(total int64, limit, offset int, sort string, count int) PageMeta {
+	meta := PageMeta{Total: total, Limit: limit, Offset: offset, Sort: sort}
+	if next := offset + count; int64(next) < total {
+		meta.HasMore = true
+		meta.NextOffset = &next
+	}
+	return meta
+}
+
+func NewBookingConflictResponse(err *models.BookingConflictError) BookingConflictResponse {
+	conflicts := make([]BookingResponse, 0, len(err.Conflicts))
+	for _, booking := range err.Conflicts {
+		conflicts = append(conflicts, BookingResponse{
+			Type:      booking.Type,
+			ID:        booking.ID,
+			StartDate: booking.StartDate,
+			EndDate:   booking.EndDate,
+		})
+	}
+	return BookingConflictResponse{Capacity: err.Capacity, Maintenance: err.Maintenance, Conflicts: conflicts}
+}
@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"os"
+	"rental-api/repository"
+	"time"
+)
+
+const defaultOverdueInterval = 15 * time.Minute
+
+// OverdueInterval reads OVERDUE_CHECK_INTERVAL (a Go duration such as "10m").
+// Zero or a negative value disables the job.
+func OverdueInterval() time.Duration {
+	value := os.Getenv("OVERDUE_CHECK_INTERVAL")
+	if value == "" {
+		return defaultOverdueInterval
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid OVERDUE_CHECK_INTERVAL %q, using %s", value, defaultOverdueInterval)
+		return defaultOverdueInterval
+	}
+	return interval
+}
+
+// StartOverdueJob checks for overdue rentals right away and then on every
+// tick until ctx is cancelled.
+func StartOverdueJob(ctx context.Context, rentals repository.RentalRepository, interval time.Duration) {
+	if interval <= 0 {
+		log.Print("Overdue rental job disabled.")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			CheckOverdue(ctx, rentals, time.Now())
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// CheckOverdue flags rentals past their due date and brings the late fees of
+// all overdue rentals up to date.
+func CheckOverdue(ctx context.Context, rentals repository.RentalRepository, now time.Time) {
+	flagged, err := rentals.FlagOverdue(ctx, now)
+	if err != nil {
+		log.Printf("Failed to flag overdue rentals: %v", err)
+		return
+	}
+
+	accrued, err := rentals.AccrueLateFees(ctx, now)
+	if err != nil {
+		log.Printf("Failed to accrue late fees: %v", err)
+		return
+	}
+
+	if flagged > 0 || accrued > 0 {
+		log.Printf("Flagged %d overdue rentals, updated late fees on %d.", flagged, accrued)
+	}
+}
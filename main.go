@@ -1,56 +1,147 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
-	"rental-api/controllers"
-	"rental-api/models"
+	"os"
+	"rental-api/config"
+	"rental-api/jobs"
+	"rental-api/migrations"
+	"rental-api/moderation"
+	"rental-api/repository"
+	"rental-api/routes"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func main() {
-	r := gin.Default()
-
-	err := models.ConnectDatabase()
+	db, err := config.InitDB()
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 		return
 	}
-	defer models.CloseDatabase()
+	defer config.CloseDB(db)
 
-	userGroup := r.Group("/users")
-	{
-		userGroup.POST("/register", controllers.RegisterUser)
-		userGroup.POST("/login", controllers.LoginUser)
-		userGroup.GET("/:id", controllers.GetUser)
-		userGroup.PUT("/:id", controllers.UpdateUser)
-		userGroup.DELETE("/:id", controllers.DeleteUser)
-	}
+	repos := repository.New(db)
 
-	maintenanceGroup := r.Group("/maintenance")
-	{
-		maintenanceGroup.POST("/", controllers.LogMaintenance)
-		maintenanceGroup.GET("/:id", controllers.GetMaintenance)
-		maintenanceGroup.GET("/", controllers.ListMaintenance)
+	if len(os.Args) > 1 {
+		runCommand(db, repos, os.Args[1], os.Args[2:])
+		return
 	}
 
-	rentalGroup := r.Group("/rentals")
-	{
-		rentalGroup.POST("/", controllers.CreateRental)
-		rentalGroup.GET("/:id", controllers.GetRental)
-		rentalGroup.GET("/", controllers.ListRentals)
-		rentalGroup.PUT("/:id/return", controllers.ReturnRental)
+	if err := checkMigrations(db); err != nil {
+		log.Fatal(err)
 	}
 
-	reviewGroup := r.Group("/reviews")
-	{
-		reviewGroup.POST("/", controllers.SubmitReview)
-		reviewGroup.GET("/:id", controllers.GetReview)
-		reviewGroup.GET("/", controllers.ListReviews)
-		reviewGroup.DELETE("/:id", controllers.DeleteReview)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	jobs.StartOverdueJob(ctx, repos.Rentals, jobs.OverdueInterval())
+
+	reviewFilter, err := moderation.DefaultFilter()
+	if err != nil {
+		log.Fatal("Failed to load review blocklists: ", err)
 	}
 
+	r := gin.Default()
+	routes.SetupRoutes(r, repos, reviewFilter)
+
 	if err := r.Run(":8080"); err != nil {
 		log.Fatal("Error starting server: ", err)
 	}
 }
+
+func checkMigrations(db *gorm.DB) error {
+	if config.AutoMigrateEnabled() {
+		applied, err := migrations.Up(db)
+		if err != nil {
+			return err
+		}
+		log.Printf("Applied %d migrations.", applied)
+		return nil
+	}
+
+	pending, err := migrations.Pending(db)
+	if err != nil {
+		return err
+	}
+	if pending > 0 {
+		return fmt.Errorf("database has %d pending migrations; run `%s migrate up` first", pending, os.Args[0])
+	}
+	return nil
+}
+
+func runCommand(db *gorm.DB, repos *repository.Repositories, name string, args []string) {
+	ctx := context.Background()
+
+	switch name {
+	case "migrate":
+		runMigrate(db, args)
+	case "hash-passwords":
+		upgraded, err := repos.Users.RehashPlaintextPasswords(ctx)
+		if err != nil {
+			log.Fatalf("Failed to hash passwords after %d users: %v", upgraded, err)
+		}
+		log.Printf("Hashed %d plaintext passwords.", upgraded)
+	case "set-role":
+		if len(args) != 2 {
+			log.Fatal("Usage: set-role <email> <role>")
+		}
+		if err := repos.Users.UpdateRoleByEmail(ctx, args[0], args[1]); err != nil {
+			log.Fatalf("Failed to set role: %v", err)
+		}
+		log.Printf("Set role of %s to %s.", args[0], args[1])
+	default:
+		log.Fatalf("Unknown command %q (available: migrate, hash-passwords, set-role)", name)
+	}
+}
+
+func runMigrate(db *gorm.DB, args []string) {
+	if len(args) == 0 {
+		log.Fatal("Usage: migrate up|down [steps]|status|baseline")
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrations.Up(db)
+		if err != nil {
+			log.Fatalf("Migration failed after applying %d: %v", applied, err)
+		}
+		log.Printf("Applied %d migrations.", applied)
+	case "baseline":
+		if err := migrations.Baseline(db); err != nil {
+			log.Fatalf("Baseline failed: %v", err)
+		}
+		log.Printf("Recorded the existing schema as migration 1; run `%s migrate up` to apply the rest.", os.Args[0])
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				log.Fatalf("Invalid number of steps %q", args[1])
+			}
+			steps = n
+		}
+		reverted, err := migrations.Down(db, steps)
+		if err != nil {
+			log.Fatalf("Rollback failed after reverting %d: %v", reverted, err)
+		}
+		log.Printf("Reverted %d migrations.", reverted)
+	case "status":
+		statuses, err := migrations.StatusOf(db)
+		if err != nil {
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			log.Printf("%04d %-40s %s", status.Version, status.Name, state)
+		}
+	default:
+		log.Fatalf("Unknown migrate command %q (expected up, down, status or baseline)", args[0])
+	}
+}
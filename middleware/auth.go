@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http"
+	"rental-api/models"
+	"rental-api/repository"
+	"rental-api/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const currentUserKey = "currentUser"
+
+func RequireAuth(users repository.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := utils.ExtractUserIDFromJWT(c)
+		if err != nil {
+			utils.RespondError(c, http.StatusUnauthorized, "Unauthorized: "+err.Error())
+			c.Abort()
+			return
+		}
+
+		user, err := users.GetByID(c.Request.Context(), int(userID))
+		if err != nil {
+			utils.RespondError(c, http.StatusUnauthorized, "Unauthorized: user not found")
+			c.Abort()
+			return
+		}
+
+		c.Set(currentUserKey, user)
+		c.Next()
+	}
+}
+
+// OptionalAuth authenticates the request like RequireAuth when it carries
+// credentials and lets anonymous requests through.
+func OptionalAuth(users repository.UserRepository) gin.HandlerFunc {
+	requireAuth := RequireAuth(users)
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		requireAuth(c)
+	}
+}
+
+// RequireRole allows the request through only when the authenticated user
+// holds one of the given roles. Admins are always allowed.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := CurrentUser(c)
+		if !ok {
+			utils.RespondError(c, http.StatusUnauthorized, "Unauthorized: authentication required")
+			c.Abort()
+			return
+		}
+
+		if !user.HasRole(roles...) {
+			utils.RespondError(c, http.StatusForbidden, "Forbidden: insufficient role")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// CurrentUser returns the user authenticated by RequireAuth.
+func CurrentUser(c *gin.Context) (*models.User, bool) {
+	value, exists := c.Get(currentUserKey)
+	if !exists {
+		return nil, false
+	}
+	user, ok := value.(*models.User)
+	return user, ok
+}
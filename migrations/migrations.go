@@ -0,0 +1,472 @@
+package migrations
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+const tableName = "schema_migrations"
+
+type Migration struct {
+	Version int
+	Name    string
+	up      string
+	down    string
+}
+
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+type appliedMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (appliedMigration) TableName() string {
+	return tableName
+}
+
+// dialect holds the column definitions that differ between drivers. The SQL
+// files refer to them as {{.ID}}, {{.Ref}}, {{.Timestamp}} and so on, quote
+// identifiers with {{quote "name"}} and drop indexes with
+// {{dropIndex "index" "table"}}. Statements only one driver understands go
+// inside {{if eq .Driver "sqlite"}}.
+type dialect struct {
+	Driver    string
+	ID        string
+	Ref       string
+	Timestamp string
+	Money     string
+	JSON      string
+	quote     string
+	indexOn   bool
+}
+
+var dialects = map[string]dialect{
+	"sqlite": {
+		Driver:    "sqlite",
+		ID:        "INTEGER PRIMARY KEY AUTOINCREMENT",
+		Ref:       "INTEGER",
+		Timestamp: "DATETIME",
+		Money:     "DECIMAL(12,2)",
+		JSON:      "TEXT",
+		quote:     `"`,
+	},
+	"mysql": {
+		Driver:    "mysql",
+		ID:        "BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY",
+		Ref:       "BIGINT UNSIGNED",
+		Timestamp: "DATETIME(3)",
+		Money:     "DECIMAL(12,2)",
+		JSON:      "LONGTEXT",
+		quote:     "`",
+		indexOn:   true,
+	},
+	"postgres": {
+		Driver:    "postgres",
+		ID:        "BIGSERIAL PRIMARY KEY",
+		Ref:       "BIGINT",
+		Timestamp: "TIMESTAMPTZ",
+		Money:     "NUMERIC(12,2)",
+		JSON:      "TEXT",
+		quote:     `"`,
+	},
+}
+
+// Load returns every embedded migration ordered by version.
+func Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		prefix, label, found := strings.Cut(base, "_")
+		if !found {
+			return nil, fmt.Errorf("migration %s: expected <version>_<name>", name)
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version: %w", name, err)
+		}
+
+		content, err := files.ReadFile(path.Join("sql", name))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		} else if m.Name != label {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, label)
+		}
+		if direction == "up" {
+			m.up = string(content)
+		} else {
+			m.down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// ErrUnversioned means the database already has the tables of the first
+// migration but no migration history, as databases created by AutoMigrate
+// before migrations existed do. Baseline adopts such a database.
+var ErrUnversioned = errors.New("database has tables but no migration history; run `migrate baseline` to adopt it")
+
+// ErrVersioned means Baseline was run on a database that already has a
+// migration history.
+var ErrVersioned = errors.New("database already has a migration history")
+
+// baselineTable is a table created by the first migration, with the columns
+// and indexes it must have for the database to be adopted.
+type baselineTable struct {
+	name    string
+	columns []string
+	indexes map[string]string
+}
+
+const baselineVersion = 1
+
+var baselineTables = []baselineTable{
+	{
+		name:    "users",
+		columns: []string{"id", "email", "password", "first_name", "last_name", "role", "created_at", "updated_at"},
+	},
+	{
+		name: "mesin_bors",
+		columns: []string{"id", "created_at", "updated_at", "deleted_at", "name", "stock_availability", "rental_costs",
+			"weekly_rate", "monthly_rate", "min_rental_days", "category", "description", "brand", "condition"},
+		indexes: map[string]string{"idx_mesin_bors_deleted_at": "deleted_at"},
+	},
+	{
+		name: "rental_histories",
+		columns: []string{"id", "user_id", "machine_id", "rental_date", "due_date", "return_date", "total_cost",
+			"cost_breakdown", "created_at", "updated_at"},
+		indexes: map[string]string{
+			"idx_rental_histories_user_id":    "user_id",
+			"idx_rental_histories_machine_id": "machine_id",
+		},
+	},
+	{
+		name:    "reviews",
+		columns: []string{"id", "user_id", "machine_id", "rating", "comment", "created_at", "updated_at"},
+		indexes: map[string]string{"idx_reviews_machine_id": "machine_id"},
+	},
+	{
+		name:    "maintenances",
+		columns: []string{"id", "machine_id", "issue", "fixed", "fixed_at", "created_at", "updated_at"},
+		indexes: map[string]string{"idx_maintenances_machine_id": "machine_id"},
+	},
+}
+
+// Up applies every pending migration in order and returns how many ran. It
+// fails with ErrUnversioned instead of running the first migration over
+// tables that already exist.
+func Up(db *gorm.DB) (int, error) {
+	migrations, applied, err := prepare(db)
+	if err != nil {
+		return 0, err
+	}
+	if len(applied) == 0 && db.Migrator().HasTable(baselineTables[0].name) {
+		return 0, ErrUnversioned
+	}
+
+	count := 0
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if err := run(db, m, m.up, func(tx *gorm.DB) error {
+			return tx.Create(&appliedMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		}); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Down rolls back the given number of most recently applied migrations.
+func Down(db *gorm.DB, steps int) (int, error) {
+	migrations, applied, err := prepare(db)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if err := run(db, m, m.down, func(tx *gorm.DB) error {
+			return tx.Delete(&appliedMigration{}, m.Version).Error
+		}); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Baseline adopts a database whose schema was created before migrations
+// existed, by AutoMigrate. It checks that every table and column of the first
+// migration is there, adds the indexes AutoMigrate did not create and records
+// the first migration as applied without running it. Up then applies the
+// rest. Databases created from the old ddl.sql have a different schema and
+// cannot be adopted; their data has to be copied into a migrated database.
+func Baseline(db *gorm.DB) error {
+	migrations, applied, err := prepare(db)
+	if err != nil {
+		return err
+	}
+	if len(applied) > 0 {
+		return ErrVersioned
+	}
+
+	migrator := db.Migrator()
+	for _, table := range baselineTables {
+		if !migrator.HasTable(table.name) {
+			return fmt.Errorf("cannot adopt the database: table %s is missing", table.name)
+		}
+		for _, column := range table.columns {
+			if !migrator.HasColumn(table.name, column) {
+				return fmt.Errorf("cannot adopt the database: column %s.%s is missing", table.name, column)
+			}
+		}
+	}
+
+	var first *Migration
+	for i := range migrations {
+		if migrations[i].Version == baselineVersion {
+			first = &migrations[i]
+		}
+	}
+	if first == nil {
+		return fmt.Errorf("migration %d not found", baselineVersion)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range baselineTables {
+			for index, column := range table.indexes {
+				if tx.Migrator().HasIndex(table.name, index) {
+					continue
+				}
+				if err := tx.Exec("CREATE INDEX " + index + " ON " + table.name + " (" + column + ")").Error; err != nil {
+					return fmt.Errorf("failed to create index %s: %w", index, err)
+				}
+			}
+		}
+		return tx.Create(&appliedMigration{Version: first.Version, Name: first.Name, AppliedAt: time.Now()}).Error
+	})
+}
+
+func StatusOf(db *gorm.DB) ([]Status, error) {
+	migrations, applied, err := prepare(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(migrations))
+	for _, m := range migrations {
+		status := Status{Version: m.Version, Name: m.Name}
+		if record, ok := applied[m.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func Pending(db *gorm.DB) (int, error) {
+	statuses, err := StatusOf(db)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := 0
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+func prepare(db *gorm.DB) ([]Migration, map[int]appliedMigration, error) {
+	if _, ok := dialects[db.Dialector.Name()]; !ok {
+		return nil, nil, fmt.Errorf("migrations do not support the %s driver", db.Dialector.Name())
+	}
+
+	createTable, err := render(db, `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version BIGINT NOT NULL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	applied_at {{.Timestamp}} NOT NULL
+)`)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := db.Exec(createTable).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to create %s: %w", tableName, err)
+	}
+
+	migrations, err := Load()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var records []appliedMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, nil, err
+	}
+	applied := make(map[int]appliedMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return migrations, applied, nil
+}
+
+// run executes one migration file and records the result in a single
+// transaction. MySQL commits DDL implicitly, so a failed migration there may
+// need manual cleanup before it can be retried.
+func run(db *gorm.DB, m Migration, script string, record func(tx *gorm.DB) error) error {
+	sql, err := render(db, script)
+	if err != nil {
+		return fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+	}
+	apply := func(tx *gorm.DB) error {
+		for _, statement := range splitStatements(sql) {
+			if err := tx.Exec(statement).Error; err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+			}
+		}
+		return record(tx)
+	}
+	if db.Dialector.Name() == "sqlite" {
+		return runWithoutForeignKeys(db, m, apply)
+	}
+	return db.Transaction(apply)
+}
+
+// runWithoutForeignKeys runs a SQLite migration with foreign keys switched
+// off, which SQLite needs to rebuild a table that other tables reference. The
+// switch only works outside a transaction and per connection, so one
+// connection is held for the whole run, and the keys are checked before the
+// migration commits.
+func runWithoutForeignKeys(db *gorm.DB, m Migration, apply func(tx *gorm.DB) error) error {
+	return db.Connection(func(conn *gorm.DB) error {
+		conn = conn.Session(&gorm.Session{})
+
+		var enabled int
+		if err := conn.Raw("PRAGMA foreign_keys").Scan(&enabled).Error; err != nil {
+			return err
+		}
+		if enabled == 0 {
+			return conn.Transaction(apply)
+		}
+
+		if err := conn.Exec("PRAGMA foreign_keys = OFF").Error; err != nil {
+			return err
+		}
+		defer conn.Exec("PRAGMA foreign_keys = ON")
+
+		return conn.Transaction(func(tx *gorm.DB) error {
+			if err := apply(tx); err != nil {
+				return err
+			}
+			rows, err := tx.Raw("PRAGMA foreign_key_check").Rows()
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+			if rows.Next() {
+				return fmt.Errorf("migration %d_%s failed: it leaves foreign keys that point nowhere", m.Version, m.Name)
+			}
+			return rows.Err()
+		})
+	})
+}
+
+func render(db *gorm.DB, script string) (string, error) {
+	d := dialects[db.Dialector.Name()]
+	tmpl, err := template.New("migration").Funcs(template.FuncMap{
+		"quote": func(name string) string { return d.quote + name + d.quote },
+		"dropIndex": func(index, table string) string {
+			if d.indexOn {
+				return "DROP INDEX " + index + " ON " + table
+			}
+			return "DROP INDEX " + index
+		},
+	}).Parse(script)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, d); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func splitStatements(sql string) []string {
+	var lines []string
+	for _, line := range strings.Split(sql, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	var statements []string
+	for _, statement := range strings.Split(strings.Join(lines, "\n"), ";") {
+		if statement = strings.TrimSpace(statement); statement != "" {
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
@@ -0,0 +1,125 @@
+package migrations
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_foreign_keys=on"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// createUnversionedSchema builds the tables of the first migration without
+// recording it, leaving out the indexes AutoMigrate never created.
+func createUnversionedSchema(t *testing.T, db *gorm.DB) {
+	t.Helper()
+
+	migrations, err := Load()
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	sql, err := render(db, migrations[0].up)
+	if err != nil {
+		t.Fatalf("failed to render first migration: %v", err)
+	}
+	for _, statement := range splitStatements(sql) {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatalf("failed to create schema: %v", err)
+		}
+	}
+	for _, index := range []string{"idx_rental_histories_user_id", "idx_reviews_machine_id"} {
+		if err := db.Exec("DROP INDEX " + index).Error; err != nil {
+			t.Fatalf("failed to drop %s: %v", index, err)
+		}
+	}
+	if err := db.Exec("INSERT INTO users (email, password) VALUES ('old@example.com', 'secret')").Error; err != nil {
+		t.Fatalf("failed to insert user: %v", err)
+	}
+}
+
+func TestBaselineAdoptsExistingSchema(t *testing.T) {
+	db := openTestDB(t)
+	createUnversionedSchema(t, db)
+
+	if _, err := Up(db); !errors.Is(err, ErrUnversioned) {
+		t.Fatalf("expected ErrUnversioned, got %v", err)
+	}
+
+	if err := Baseline(db); err != nil {
+		t.Fatalf("failed to baseline: %v", err)
+	}
+	if !db.Migrator().HasIndex("rental_histories", "idx_rental_histories_user_id") {
+		t.Errorf("expected baseline to create the missing index")
+	}
+	if err := Baseline(db); !errors.Is(err, ErrVersioned) {
+		t.Errorf("expected ErrVersioned on a second baseline, got %v", err)
+	}
+
+	migrations, err := Load()
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	applied, err := Up(db)
+	if err != nil {
+		t.Fatalf("failed to migrate after baseline: %v", err)
+	}
+	if applied != len(migrations)-1 {
+		t.Errorf("expected %d migrations to run after baseline, got %d", len(migrations)-1, applied)
+	}
+
+	var users int64
+	if err := db.Table("users").Count(&users).Error; err != nil {
+		t.Fatalf("failed to count users: %v", err)
+	}
+	if users != 1 {
+		t.Errorf("expected the existing user to survive, got %d users", users)
+	}
+}
+
+func TestBaselineRejectsForeignSchema(t *testing.T) {
+	db := openTestDB(t)
+
+	// The old ddl.sql named its tables differently.
+	if err := db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, email VARCHAR(255), password VARCHAR(255))").Error; err != nil {
+		t.Fatalf("failed to create users: %v", err)
+	}
+	if err := Baseline(db); err == nil {
+		t.Fatalf("expected baseline to refuse an incomplete schema")
+	}
+
+	var count int64
+	if err := db.Table(tableName).Count(&count).Error; err != nil {
+		t.Fatalf("failed to count applied migrations: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("expected no migration to be recorded, got %d", count)
+	}
+}
+
+func TestUpOnEmptyDatabase(t *testing.T) {
+	db := openTestDB(t)
+
+	if _, err := Up(db); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if err := Baseline(db); !errors.Is(err, ErrVersioned) {
+		t.Errorf("expected ErrVersioned after up, got %v", err)
+	}
+}
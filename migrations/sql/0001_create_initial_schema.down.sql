@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS maintenances;
+DROP TABLE IF EXISTS reviews;
+DROP TABLE IF EXISTS rental_histories;
+DROP TABLE IF EXISTS mesin_bors;
+DROP TABLE IF EXISTS users;
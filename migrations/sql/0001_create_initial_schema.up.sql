@@ -0,0 +1,74 @@
+CREATE TABLE users (
+    id {{.ID}},
+    email VARCHAR(255) NOT NULL UNIQUE,
+    password VARCHAR(255) NOT NULL,
+    first_name VARCHAR(255) NOT NULL DEFAULT '',
+    last_name VARCHAR(255) NOT NULL DEFAULT '',
+    role VARCHAR(20) NOT NULL DEFAULT 'customer' CHECK (role IN ('customer', 'staff', 'technician', 'admin')),
+    created_at {{.Timestamp}},
+    updated_at {{.Timestamp}}
+);
+
+CREATE TABLE mesin_bors (
+    id {{.ID}},
+    created_at {{.Timestamp}},
+    updated_at {{.Timestamp}},
+    deleted_at {{.Timestamp}} NULL,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    stock_availability INT NOT NULL DEFAULT 0,
+    rental_costs {{.Money}} NOT NULL DEFAULT 0,
+    weekly_rate {{.Money}} NOT NULL DEFAULT 0,
+    monthly_rate {{.Money}} NOT NULL DEFAULT 0,
+    min_rental_days INT NOT NULL DEFAULT 1,
+    category VARCHAR(100) DEFAULT 'Uncategorized',
+    description VARCHAR(255),
+    brand VARCHAR(100),
+    {{quote "condition"}} VARCHAR(20) DEFAULT 'Good' CHECK ({{quote "condition"}} IN ('Good', 'Damaged', 'Needs Maintenance'))
+);
+
+CREATE INDEX idx_mesin_bors_deleted_at ON mesin_bors (deleted_at);
+
+CREATE TABLE rental_histories (
+    id {{.ID}},
+    user_id {{.Ref}} NOT NULL,
+    machine_id {{.Ref}} NOT NULL,
+    rental_date {{.Timestamp}} NOT NULL,
+    due_date {{.Timestamp}} NULL,
+    return_date {{.Timestamp}} NULL,
+    total_cost {{.Money}} NOT NULL DEFAULT 0,
+    cost_breakdown {{.JSON}},
+    created_at {{.Timestamp}},
+    updated_at {{.Timestamp}},
+    FOREIGN KEY (user_id) REFERENCES users (id),
+    FOREIGN KEY (machine_id) REFERENCES mesin_bors (id)
+);
+
+CREATE INDEX idx_rental_histories_user_id ON rental_histories (user_id);
+CREATE INDEX idx_rental_histories_machine_id ON rental_histories (machine_id);
+
+CREATE TABLE reviews (
+    id {{.ID}},
+    user_id {{.Ref}} NOT NULL,
+    machine_id {{.Ref}} NOT NULL,
+    rating INT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    comment TEXT,
+    created_at {{.Timestamp}},
+    updated_at {{.Timestamp}},
+    FOREIGN KEY (user_id) REFERENCES users (id),
+    FOREIGN KEY (machine_id) REFERENCES mesin_bors (id)
+);
+
+CREATE INDEX idx_reviews_machine_id ON reviews (machine_id);
+
+CREATE TABLE maintenances (
+    id {{.ID}},
+    machine_id {{.Ref}} NOT NULL,
+    issue TEXT,
+    fixed BOOLEAN NOT NULL DEFAULT FALSE,
+    fixed_at {{.Timestamp}} NULL,
+    created_at {{.Timestamp}},
+    updated_at {{.Timestamp}},
+    FOREIGN KEY (machine_id) REFERENCES mesin_bors (id)
+);
+
+CREATE INDEX idx_maintenances_machine_id ON maintenances (machine_id);
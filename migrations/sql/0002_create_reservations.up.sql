@@ -0,0 +1,16 @@
+CREATE TABLE reservations (
+    id {{.ID}},
+    user_id {{.Ref}} NOT NULL,
+    machine_id {{.Ref}} NOT NULL,
+    start_date {{.Timestamp}} NOT NULL,
+    end_date {{.Timestamp}} NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'booked' CHECK (status IN ('booked', 'cancelled', 'fulfilled')),
+    notes TEXT,
+    created_at {{.Timestamp}},
+    updated_at {{.Timestamp}},
+    FOREIGN KEY (user_id) REFERENCES users (id),
+    FOREIGN KEY (machine_id) REFERENCES mesin_bors (id)
+);
+
+CREATE INDEX idx_reservations_machine_period ON reservations (machine_id, start_date, end_date);
+CREATE INDEX idx_reservations_user_id ON reservations (user_id);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS rental_status_events;
+
+{{dropIndex "idx_rental_histories_status" "rental_histories"}};
+
+ALTER TABLE rental_histories DROP COLUMN status;
@@ -0,0 +1,20 @@
+ALTER TABLE rental_histories ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'requested' CHECK (status IN ('requested', 'confirmed', 'picked_up', 'active', 'overdue', 'returned', 'cancelled'));
+
+UPDATE rental_histories SET status = 'returned' WHERE return_date IS NOT NULL;
+UPDATE rental_histories SET status = 'active' WHERE return_date IS NULL;
+
+CREATE INDEX idx_rental_histories_status ON rental_histories (status);
+
+CREATE TABLE rental_status_events (
+    id {{.ID}},
+    rental_id {{.Ref}} NOT NULL,
+    from_status VARCHAR(20) NOT NULL DEFAULT '',
+    to_status VARCHAR(20) NOT NULL,
+    actor_id {{.Ref}} NULL,
+    note TEXT,
+    created_at {{.Timestamp}},
+    FOREIGN KEY (rental_id) REFERENCES rental_histories (id),
+    FOREIGN KEY (actor_id) REFERENCES users (id)
+);
+
+CREATE INDEX idx_rental_status_events_rental_id ON rental_status_events (rental_id);
@@ -0,0 +1,6 @@
+{{dropIndex "idx_rental_histories_due_date" "rental_histories"}};
+
+ALTER TABLE rental_histories DROP COLUMN overdue_days;
+ALTER TABLE rental_histories DROP COLUMN late_fee;
+
+DROP TABLE IF EXISTS late_fee_policies;
@@ -0,0 +1,18 @@
+CREATE TABLE late_fee_policies (
+    id {{.ID}},
+    machine_id {{.Ref}} NULL UNIQUE,
+    category VARCHAR(100) NULL UNIQUE,
+    daily_fee {{.Money}} NOT NULL DEFAULT 0,
+    rate_multiplier DECIMAL(6,3) NOT NULL DEFAULT 0,
+    grace_hours INT NOT NULL DEFAULT 0,
+    max_fee {{.Money}} NOT NULL DEFAULT 0,
+    created_at {{.Timestamp}},
+    updated_at {{.Timestamp}},
+    CHECK ((machine_id IS NULL) <> (category IS NULL)),
+    FOREIGN KEY (machine_id) REFERENCES mesin_bors (id)
+);
+
+ALTER TABLE rental_histories ADD COLUMN late_fee {{.Money}} NOT NULL DEFAULT 0;
+ALTER TABLE rental_histories ADD COLUMN overdue_days INT NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_rental_histories_due_date ON rental_histories (due_date);
@@ -0,0 +1,15 @@
+CREATE TABLE rental_extensions (
+    id {{.ID}},
+    rental_id {{.Ref}} NOT NULL,
+    previous_due_date {{.Timestamp}} NOT NULL,
+    new_due_date {{.Timestamp}} NOT NULL,
+    previous_cost {{.Money}} NOT NULL DEFAULT 0,
+    additional_cost {{.Money}} NOT NULL DEFAULT 0,
+    actor_id {{.Ref}} NULL,
+    note TEXT,
+    created_at {{.Timestamp}},
+    FOREIGN KEY (rental_id) REFERENCES rental_histories (id),
+    FOREIGN KEY (actor_id) REFERENCES users (id)
+);
+
+CREATE INDEX idx_rental_extensions_rental_id ON rental_extensions (rental_id);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS deposit_entries;
+
+ALTER TABLE mesin_bors DROP COLUMN deposit_rate;
+ALTER TABLE mesin_bors DROP COLUMN deposit_amount;
@@ -0,0 +1,19 @@
+ALTER TABLE mesin_bors ADD COLUMN deposit_amount {{.Money}} NOT NULL DEFAULT 0;
+ALTER TABLE mesin_bors ADD COLUMN deposit_rate DECIMAL(6,3) NOT NULL DEFAULT 0;
+
+CREATE TABLE deposit_entries (
+    id {{.ID}},
+    user_id {{.Ref}} NOT NULL,
+    rental_id {{.Ref}} NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('credit', 'hold', 'release', 'forfeit', 'refund')),
+    amount {{.Money}} NOT NULL CHECK (amount > 0),
+    reason TEXT,
+    actor_id {{.Ref}} NULL,
+    created_at {{.Timestamp}},
+    FOREIGN KEY (user_id) REFERENCES users (id),
+    FOREIGN KEY (rental_id) REFERENCES rental_histories (id),
+    FOREIGN KEY (actor_id) REFERENCES users (id)
+);
+
+CREATE INDEX idx_deposit_entries_user_id ON deposit_entries (user_id);
+CREATE INDEX idx_deposit_entries_rental_id ON deposit_entries (rental_id);
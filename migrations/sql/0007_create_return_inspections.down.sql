@@ -0,0 +1,3 @@
+ALTER TABLE rental_histories DROP COLUMN damage_charge;
+
+DROP TABLE IF EXISTS return_inspections;
@@ -0,0 +1,16 @@
+CREATE TABLE return_inspections (
+    id {{.ID}},
+    rental_id {{.Ref}} NOT NULL UNIQUE,
+    inspector_id {{.Ref}} NULL,
+    {{quote "condition"}} VARCHAR(20) NOT NULL CHECK ({{quote "condition"}} IN ('Good', 'Damaged', 'Needs Maintenance')),
+    notes TEXT,
+    damage_items {{.JSON}},
+    damage_charge {{.Money}} NOT NULL DEFAULT 0,
+    maintenance_id {{.Ref}} NULL,
+    created_at {{.Timestamp}},
+    FOREIGN KEY (rental_id) REFERENCES rental_histories (id),
+    FOREIGN KEY (inspector_id) REFERENCES users (id),
+    FOREIGN KEY (maintenance_id) REFERENCES maintenances (id)
+);
+
+ALTER TABLE rental_histories ADD COLUMN damage_charge {{.Money}} NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+{{dropIndex "idx_rental_histories_order_id" "rental_histories"}};
+
+ALTER TABLE rental_histories DROP COLUMN order_line_id;
+ALTER TABLE rental_histories DROP COLUMN order_id;
+
+DROP TABLE IF EXISTS rental_order_lines;
+DROP TABLE IF EXISTS rental_orders;
@@ -0,0 +1,31 @@
+CREATE TABLE rental_orders (
+    id {{.ID}},
+    user_id {{.Ref}} NOT NULL,
+    rental_date {{.Timestamp}} NOT NULL,
+    due_date {{.Timestamp}} NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'partially_returned', 'returned', 'cancelled')),
+    total_cost {{.Money}} NOT NULL DEFAULT 0,
+    notes TEXT,
+    created_at {{.Timestamp}},
+    updated_at {{.Timestamp}},
+    FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE INDEX idx_rental_orders_user_id ON rental_orders (user_id);
+
+CREATE TABLE rental_order_lines (
+    id {{.ID}},
+    order_id {{.Ref}} NOT NULL,
+    machine_id {{.Ref}} NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    created_at {{.Timestamp}},
+    FOREIGN KEY (order_id) REFERENCES rental_orders (id),
+    FOREIGN KEY (machine_id) REFERENCES mesin_bors (id)
+);
+
+CREATE INDEX idx_rental_order_lines_order_id ON rental_order_lines (order_id);
+
+ALTER TABLE rental_histories ADD COLUMN order_id {{.Ref}} NULL;
+ALTER TABLE rental_histories ADD COLUMN order_line_id {{.Ref}} NULL;
+
+CREATE INDEX idx_rental_histories_order_id ON rental_histories (order_id);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS invoice_taxes;
+DROP TABLE IF EXISTS invoice_lines;
+DROP TABLE IF EXISTS invoices;
+DROP TABLE IF EXISTS invoice_sequences;
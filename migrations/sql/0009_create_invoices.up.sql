@@ -0,0 +1,54 @@
+CREATE TABLE invoice_sequences (
+    name VARCHAR(50) PRIMARY KEY,
+    next_value INT NOT NULL
+);
+
+INSERT INTO invoice_sequences (name, next_value) VALUES ('invoice', 1);
+
+CREATE TABLE invoices (
+    id {{.ID}},
+    number VARCHAR(30) NOT NULL UNIQUE,
+    user_id {{.Ref}} NOT NULL,
+    rental_id {{.Ref}} NULL UNIQUE,
+    order_id {{.Ref}} NULL UNIQUE,
+    status VARCHAR(20) NOT NULL DEFAULT 'issued' CHECK (status IN ('issued', 'paid', 'void')),
+    issue_date {{.Timestamp}} NOT NULL,
+    period_start {{.Timestamp}} NOT NULL,
+    period_end {{.Timestamp}} NOT NULL,
+    subtotal {{.Money}} NOT NULL DEFAULT 0,
+    tax_total {{.Money}} NOT NULL DEFAULT 0,
+    total {{.Money}} NOT NULL DEFAULT 0,
+    created_at {{.Timestamp}},
+    updated_at {{.Timestamp}},
+    CHECK ((rental_id IS NULL) <> (order_id IS NULL)),
+    FOREIGN KEY (user_id) REFERENCES users (id),
+    FOREIGN KEY (rental_id) REFERENCES rental_histories (id),
+    FOREIGN KEY (order_id) REFERENCES rental_orders (id)
+);
+
+CREATE INDEX idx_invoices_user_id ON invoices (user_id);
+
+CREATE TABLE invoice_lines (
+    id {{.ID}},
+    invoice_id {{.Ref}} NOT NULL,
+    position INT NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    description VARCHAR(255) NOT NULL,
+    quantity INT NOT NULL DEFAULT 1,
+    unit_price {{.Money}} NOT NULL DEFAULT 0,
+    amount {{.Money}} NOT NULL DEFAULT 0,
+    FOREIGN KEY (invoice_id) REFERENCES invoices (id)
+);
+
+CREATE INDEX idx_invoice_lines_invoice_id ON invoice_lines (invoice_id);
+
+CREATE TABLE invoice_taxes (
+    id {{.ID}},
+    invoice_id {{.Ref}} NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    rate DECIMAL(6,4) NOT NULL,
+    amount {{.Money}} NOT NULL DEFAULT 0,
+    FOREIGN KEY (invoice_id) REFERENCES invoices (id)
+);
+
+CREATE INDEX idx_invoice_taxes_invoice_id ON invoice_taxes (invoice_id);
@@ -0,0 +1,37 @@
+ALTER TABLE invoices ADD COLUMN amount_paid {{.Money}} NOT NULL DEFAULT 0;
+
+CREATE TABLE payments (
+    id {{.ID}},
+    invoice_id {{.Ref}} NOT NULL,
+    user_id {{.Ref}} NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('payment', 'refund')),
+    method VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    amount {{.Money}} NOT NULL CHECK (amount > 0),
+    external_id VARCHAR(255) NULL,
+    reference VARCHAR(255),
+    note TEXT,
+    refund_of_id {{.Ref}} NULL,
+    actor_id {{.Ref}} NULL,
+    created_at {{.Timestamp}},
+    updated_at {{.Timestamp}},
+    UNIQUE (method, external_id),
+    FOREIGN KEY (invoice_id) REFERENCES invoices (id),
+    FOREIGN KEY (user_id) REFERENCES users (id),
+    FOREIGN KEY (refund_of_id) REFERENCES payments (id),
+    FOREIGN KEY (actor_id) REFERENCES users (id)
+);
+
+CREATE INDEX idx_payments_invoice_id ON payments (invoice_id);
+CREATE INDEX idx_payments_user_id ON payments (user_id);
+
+CREATE TABLE webhook_events (
+    id {{.ID}},
+    gateway VARCHAR(50) NOT NULL,
+    event_id VARCHAR(255) NOT NULL,
+    external_id VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    payload TEXT,
+    created_at {{.Timestamp}},
+    UNIQUE (gateway, event_id)
+);
@@ -0,0 +1,3 @@
+{{dropIndex "idx_reviews_rental_id" "reviews"}};
+
+ALTER TABLE reviews DROP COLUMN rental_id;
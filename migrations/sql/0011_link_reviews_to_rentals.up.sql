@@ -0,0 +1,4 @@
+ALTER TABLE reviews ADD COLUMN rental_id {{.Ref}} NULL;
+
+-- Reviews written before this migration have no rental and stay unverified.
+CREATE UNIQUE INDEX idx_reviews_rental_id ON reviews (rental_id);
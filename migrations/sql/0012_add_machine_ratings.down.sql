@@ -0,0 +1,11 @@
+{{dropIndex "idx_mesin_bors_rating_score" "mesin_bors"}};
+
+ALTER TABLE mesin_bors DROP COLUMN rating_score;
+ALTER TABLE mesin_bors DROP COLUMN rating_average;
+ALTER TABLE mesin_bors DROP COLUMN rating_5_count;
+ALTER TABLE mesin_bors DROP COLUMN rating_4_count;
+ALTER TABLE mesin_bors DROP COLUMN rating_3_count;
+ALTER TABLE mesin_bors DROP COLUMN rating_2_count;
+ALTER TABLE mesin_bors DROP COLUMN rating_1_count;
+ALTER TABLE mesin_bors DROP COLUMN rating_sum;
+ALTER TABLE mesin_bors DROP COLUMN rating_count;
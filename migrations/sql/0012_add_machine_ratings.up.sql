@@ -0,0 +1,25 @@
+ALTER TABLE mesin_bors ADD COLUMN rating_count INT NOT NULL DEFAULT 0;
+ALTER TABLE mesin_bors ADD COLUMN rating_sum INT NOT NULL DEFAULT 0;
+ALTER TABLE mesin_bors ADD COLUMN rating_1_count INT NOT NULL DEFAULT 0;
+ALTER TABLE mesin_bors ADD COLUMN rating_2_count INT NOT NULL DEFAULT 0;
+ALTER TABLE mesin_bors ADD COLUMN rating_3_count INT NOT NULL DEFAULT 0;
+ALTER TABLE mesin_bors ADD COLUMN rating_4_count INT NOT NULL DEFAULT 0;
+ALTER TABLE mesin_bors ADD COLUMN rating_5_count INT NOT NULL DEFAULT 0;
+ALTER TABLE mesin_bors ADD COLUMN rating_average DECIMAL(6,4) NOT NULL DEFAULT 0;
+-- Machines without reviews start at the prior mean used by models.BayesianScore.
+ALTER TABLE mesin_bors ADD COLUMN rating_score DECIMAL(6,4) NOT NULL DEFAULT 3;
+
+UPDATE mesin_bors SET
+    rating_count = (SELECT COUNT(*) FROM reviews WHERE reviews.machine_id = mesin_bors.id),
+    rating_sum = (SELECT COALESCE(SUM(rating), 0) FROM reviews WHERE reviews.machine_id = mesin_bors.id),
+    rating_1_count = (SELECT COUNT(*) FROM reviews WHERE reviews.machine_id = mesin_bors.id AND rating = 1),
+    rating_2_count = (SELECT COUNT(*) FROM reviews WHERE reviews.machine_id = mesin_bors.id AND rating = 2),
+    rating_3_count = (SELECT COUNT(*) FROM reviews WHERE reviews.machine_id = mesin_bors.id AND rating = 3),
+    rating_4_count = (SELECT COUNT(*) FROM reviews WHERE reviews.machine_id = mesin_bors.id AND rating = 4),
+    rating_5_count = (SELECT COUNT(*) FROM reviews WHERE reviews.machine_id = mesin_bors.id AND rating = 5);
+
+UPDATE mesin_bors SET
+    rating_average = CASE WHEN rating_count > 0 THEN ROUND(rating_sum * 1.0 / rating_count, 4) ELSE 0 END,
+    rating_score = ROUND((5 * 3.0 + rating_sum) / (5 + rating_count), 4);
+
+CREATE INDEX idx_mesin_bors_rating_score ON mesin_bors (rating_score);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS review_reports;
+
+{{dropIndex "idx_reviews_status" "reviews"}};
+
+ALTER TABLE reviews DROP COLUMN moderated_at;
+ALTER TABLE reviews DROP COLUMN moderated_by;
+ALTER TABLE reviews DROP COLUMN moderation_reason;
+ALTER TABLE reviews DROP COLUMN status;
@@ -0,0 +1,19 @@
+-- Reviews already published stay visible.
+ALTER TABLE reviews ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'approved' CHECK (status IN ('pending', 'approved', 'rejected'));
+ALTER TABLE reviews ADD COLUMN moderation_reason TEXT;
+ALTER TABLE reviews ADD COLUMN moderated_by {{.Ref}} NULL;
+ALTER TABLE reviews ADD COLUMN moderated_at {{.Timestamp}} NULL;
+
+CREATE INDEX idx_reviews_status ON reviews (status);
+
+CREATE TABLE review_reports (
+    id {{.ID}},
+    review_id {{.Ref}} NOT NULL,
+    reporter_id {{.Ref}} NOT NULL,
+    reason TEXT,
+    resolved BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at {{.Timestamp}},
+    UNIQUE (review_id, reporter_id),
+    FOREIGN KEY (review_id) REFERENCES reviews (id),
+    FOREIGN KEY (reporter_id) REFERENCES users (id)
+);
@@ -0,0 +1,24 @@
+CREATE TABLE review_replies (
+    id {{.ID}},
+    review_id {{.Ref}} NOT NULL UNIQUE,
+    author_id {{.Ref}} NOT NULL,
+    body TEXT NOT NULL,
+    created_at {{.Timestamp}},
+    updated_at {{.Timestamp}},
+    FOREIGN KEY (review_id) REFERENCES reviews (id),
+    FOREIGN KEY (author_id) REFERENCES users (id)
+);
+
+CREATE TABLE notifications (
+    id {{.ID}},
+    user_id {{.Ref}} NOT NULL,
+    kind VARCHAR(50) NOT NULL,
+    message TEXT NOT NULL,
+    resource_type VARCHAR(50),
+    resource_id {{.Ref}} NULL,
+    read_at {{.Timestamp}} NULL,
+    created_at {{.Timestamp}},
+    FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE INDEX idx_notifications_user_id ON notifications (user_id);
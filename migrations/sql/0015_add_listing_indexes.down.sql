@@ -0,0 +1,4 @@
+{{dropIndex "idx_maintenances_created_at" "maintenances"}};
+{{dropIndex "idx_rental_histories_rental_date" "rental_histories"}};
+{{dropIndex "idx_reviews_user_id" "reviews"}};
+{{dropIndex "idx_reviews_status_created_at" "reviews"}};
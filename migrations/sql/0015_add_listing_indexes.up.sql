@@ -0,0 +1,5 @@
+-- Support the default sort orders and date filters of the paginated listings.
+CREATE INDEX idx_reviews_status_created_at ON reviews (status, created_at);
+CREATE INDEX idx_reviews_user_id ON reviews (user_id);
+CREATE INDEX idx_rental_histories_rental_date ON rental_histories (rental_date);
+CREATE INDEX idx_maintenances_created_at ON maintenances (created_at);
@@ -0,0 +1,47 @@
+-- Fails while a rental or order has both a void and a reissued invoice.
+ALTER TABLE invoices DROP COLUMN deposit_applied;
+
+{{if eq .Driver "mysql"}}
+ALTER TABLE invoices ADD UNIQUE INDEX rental_id (rental_id);
+ALTER TABLE invoices ADD UNIQUE INDEX order_id (order_id);
+-- Dropping the generated columns drops their unique indexes with them.
+ALTER TABLE invoices DROP COLUMN active_rental_id, DROP COLUMN active_order_id;
+{{end}}
+{{dropIndex "idx_invoices_rental_id" "invoices"}};
+{{dropIndex "idx_invoices_order_id" "invoices"}};
+{{if eq .Driver "sqlite"}}
+CREATE TABLE invoices_old (
+    id {{.ID}},
+    number VARCHAR(30) NOT NULL UNIQUE,
+    user_id {{.Ref}} NOT NULL,
+    rental_id {{.Ref}} NULL UNIQUE,
+    order_id {{.Ref}} NULL UNIQUE,
+    status VARCHAR(20) NOT NULL DEFAULT 'issued' CHECK (status IN ('issued', 'paid', 'void')),
+    issue_date {{.Timestamp}} NOT NULL,
+    period_start {{.Timestamp}} NOT NULL,
+    period_end {{.Timestamp}} NOT NULL,
+    subtotal {{.Money}} NOT NULL DEFAULT 0,
+    tax_total {{.Money}} NOT NULL DEFAULT 0,
+    total {{.Money}} NOT NULL DEFAULT 0,
+    created_at {{.Timestamp}},
+    updated_at {{.Timestamp}},
+    amount_paid {{.Money}} NOT NULL DEFAULT 0,
+    CHECK ((rental_id IS NULL) <> (order_id IS NULL)),
+    FOREIGN KEY (user_id) REFERENCES users (id),
+    FOREIGN KEY (rental_id) REFERENCES rental_histories (id),
+    FOREIGN KEY (order_id) REFERENCES rental_orders (id)
+);
+
+INSERT INTO invoices_old (id, number, user_id, rental_id, order_id, status, issue_date, period_start, period_end,
+    subtotal, tax_total, total, created_at, updated_at, amount_paid)
+SELECT id, number, user_id, rental_id, order_id, status, issue_date, period_start, period_end,
+    subtotal, tax_total, total, created_at, updated_at, amount_paid
+FROM invoices;
+
+DROP TABLE invoices;
+ALTER TABLE invoices_old RENAME TO invoices;
+CREATE INDEX idx_invoices_user_id ON invoices (user_id);
+{{else if eq .Driver "postgres"}}
+ALTER TABLE invoices ADD CONSTRAINT invoices_rental_id_key UNIQUE (rental_id);
+ALTER TABLE invoices ADD CONSTRAINT invoices_order_id_key UNIQUE (order_id);
+{{end}}
@@ -0,0 +1,59 @@
+-- A void invoice no longer blocks a new one for the same rental or order:
+-- the one-invoice-per-rental rule only covers invoices that are not void.
+{{if eq .Driver "sqlite"}}
+CREATE TABLE invoices_new (
+    id {{.ID}},
+    number VARCHAR(30) NOT NULL UNIQUE,
+    user_id {{.Ref}} NOT NULL,
+    rental_id {{.Ref}} NULL,
+    order_id {{.Ref}} NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'issued' CHECK (status IN ('issued', 'paid', 'void')),
+    issue_date {{.Timestamp}} NOT NULL,
+    period_start {{.Timestamp}} NOT NULL,
+    period_end {{.Timestamp}} NOT NULL,
+    subtotal {{.Money}} NOT NULL DEFAULT 0,
+    tax_total {{.Money}} NOT NULL DEFAULT 0,
+    total {{.Money}} NOT NULL DEFAULT 0,
+    created_at {{.Timestamp}},
+    updated_at {{.Timestamp}},
+    amount_paid {{.Money}} NOT NULL DEFAULT 0,
+    CHECK ((rental_id IS NULL) <> (order_id IS NULL)),
+    FOREIGN KEY (user_id) REFERENCES users (id),
+    FOREIGN KEY (rental_id) REFERENCES rental_histories (id),
+    FOREIGN KEY (order_id) REFERENCES rental_orders (id)
+);
+
+INSERT INTO invoices_new (id, number, user_id, rental_id, order_id, status, issue_date, period_start, period_end,
+    subtotal, tax_total, total, created_at, updated_at, amount_paid)
+SELECT id, number, user_id, rental_id, order_id, status, issue_date, period_start, period_end,
+    subtotal, tax_total, total, created_at, updated_at, amount_paid
+FROM invoices;
+
+DROP TABLE invoices;
+ALTER TABLE invoices_new RENAME TO invoices;
+CREATE INDEX idx_invoices_user_id ON invoices (user_id);
+{{else if eq .Driver "postgres"}}
+ALTER TABLE invoices DROP CONSTRAINT invoices_rental_id_key;
+ALTER TABLE invoices DROP CONSTRAINT invoices_order_id_key;
+{{else}}
+-- The foreign keys need an index of their own before the unique ones go.
+CREATE INDEX idx_invoices_rental_id ON invoices (rental_id);
+CREATE INDEX idx_invoices_order_id ON invoices (order_id);
+ALTER TABLE invoices DROP INDEX rental_id;
+ALTER TABLE invoices DROP INDEX order_id;
+-- MySQL has no partial indexes, so the rule is kept on generated columns that
+-- hold the rental or order only while the invoice is not void. A unique index
+-- lets any number of rows be NULL.
+ALTER TABLE invoices
+    ADD COLUMN active_rental_id {{.Ref}} AS (CASE WHEN status <> 'void' THEN rental_id END) VIRTUAL,
+    ADD COLUMN active_order_id {{.Ref}} AS (CASE WHEN status <> 'void' THEN order_id END) VIRTUAL;
+CREATE UNIQUE INDEX idx_invoices_active_rental_id ON invoices (active_rental_id);
+CREATE UNIQUE INDEX idx_invoices_active_order_id ON invoices (active_order_id);
+{{end}}
+{{if ne .Driver "mysql"}}
+CREATE UNIQUE INDEX idx_invoices_rental_id ON invoices (rental_id) WHERE status <> 'void';
+CREATE UNIQUE INDEX idx_invoices_order_id ON invoices (order_id) WHERE status <> 'void';
+{{end}}
+-- Late fees and damage already kept from the deposit are credited on the
+-- invoice so they are not paid twice.
+ALTER TABLE invoices ADD COLUMN deposit_applied {{.Money}} NOT NULL DEFAULT 0;
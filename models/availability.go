@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+const DateLayout = "2006-01-02"
+
+type DayAvailability struct {
+	Date        string `json:"date"`
+	Total       int    `json:"total"`
+	Booked      int    `json:"booked"`
+	Maintenance int    `json:"maintenance"`
+	Available   int    `json:"available"`
+}
+
+type Availability struct {
+	MachineID uint              `json:"machine_id"`
+	Capacity  int               `json:"capacity"`
+	From      string            `json:"from"`
+	To        string            `json:"to"`
+	Days      []DayAvailability `json:"days"`
+}
+
+// ActiveDuring reports whether the maintenance record kept its
+// unit out of service at some point within [start, end).
+func (m *Maintenance) ActiveDuring(start, end time.Time) bool {
+	if !m.CreatedAt.Before(end) {
+		return false
+	}
+	if !m.Fixed {
+		return true
+	}
+	return m.FixedAt != nil && m.FixedAt.After(start)
+}
+
+// ComputeAvailability counts the free units for every day from "from" to "to"
+// inclusive. A day's booked count is the highest number of bookings active at
+// the same moment during that day.
+func ComputeAvailability(machineID uint, capacity int, bookings []Booking, maintenance []Maintenance, from, to time.Time) *Availability {
+	availability := &Availability{
+		MachineID: machineID,
+		Capacity:  capacity,
+		From:      from.Format(DateLayout),
+		To:        to.Format(DateLayout),
+		Days:      []DayAvailability{},
+	}
+
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		next := day.AddDate(0, 0, 1)
+
+		inMaintenance := 0
+		for i := range maintenance {
+			if maintenance[i].ActiveDuring(day, next) {
+				inMaintenance++
+			}
+		}
+
+		booked := PeakOverlap(bookings, day, next)
+		available := capacity - booked - inMaintenance
+		if available < 0 {
+			available = 0
+		}
+
+		availability.Days = append(availability.Days, DayAvailability{
+			Date:        day.Format(DateLayout),
+			Total:       capacity,
+			Booked:      booked,
+			Maintenance: inMaintenance,
+			Available:   available,
+		})
+	}
+	return availability
+}
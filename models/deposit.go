@@ -0,0 +1,73 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+const (
+	DepositCredit  = "credit"
+	DepositHold    = "hold"
+	DepositRelease = "release"
+	DepositForfeit = "forfeit"
+	DepositRefund  = "refund"
+)
+
+var (
+	ErrInvalidDepositAmount = errors.New("deposit amount must be positive")
+	ErrInsufficientDeposit  = errors.New("not enough available deposit")
+)
+
+// DepositEntry is one line of a user's deposit ledger. Credits and refunds
+// move money in and out of the account; holds, releases and forfeits track
+// the part tied to a rental.
+type DepositEntry struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id"`
+	RentalID  *uint     `json:"rental_id"`
+	Kind      string    `json:"kind"`
+	Amount    float64   `json:"amount"`
+	Reason    string    `json:"reason"`
+	ActorID   *uint     `json:"actor_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DepositBalance sums a user's ledger. Total is what the user has paid in and
+// not lost or had refunded, Held is the part tied to open rentals. Available
+// is negative when the holds exceed what the user has paid in, i.e. the
+// difference still has to be collected.
+type DepositBalance struct {
+	UserID    uint    `json:"user_id"`
+	Total     float64 `json:"total"`
+	Held      float64 `json:"held"`
+	Available float64 `json:"available"`
+}
+
+// NewDepositBalance builds a balance from the ledger totals per entry kind.
+func NewDepositBalance(userID uint, totals map[string]float64) DepositBalance {
+	total := totals[DepositCredit] - totals[DepositForfeit] - totals[DepositRefund]
+	held := totals[DepositHold] - totals[DepositRelease] - totals[DepositForfeit]
+	return DepositBalance{
+		UserID:    userID,
+		Total:     roundCents(total),
+		Held:      roundCents(held),
+		Available: roundCents(total - held),
+	}
+}
+
+// DepositCharge is an amount kept from a rental's deposit on return.
+type DepositCharge struct {
+	Amount float64
+	Reason string
+}
+
+// DepositFor sizes the deposit for a rental of the machine: a fixed amount
+// plus a share of the rental cost.
+func (m *MesinBor) DepositFor(rentalCost float64) float64 {
+	return roundCents(m.DepositAmount + m.DepositRate*rentalCost)
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
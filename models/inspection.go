@@ -0,0 +1,80 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	ConditionGood             = "Good"
+	ConditionDamaged          = "Damaged"
+	ConditionNeedsMaintenance = "Needs Maintenance"
+)
+
+var ErrInvalidInspection = errors.New("invalid inspection")
+
+func IsValidCondition(condition string) bool {
+	switch condition {
+	case ConditionGood, ConditionDamaged, ConditionNeedsMaintenance:
+		return true
+	}
+	return false
+}
+
+type DamageItem struct {
+	Description string  `json:"description"`
+	Cost        float64 `json:"cost"`
+}
+
+// ReturnInspection is the state staff found the machine in when a rental came
+// back. MaintenanceID points at the ticket opened for it, if any.
+type ReturnInspection struct {
+	ID            uint         `json:"id" gorm:"primaryKey"`
+	RentalID      uint         `json:"rental_id"`
+	InspectorID   *uint        `json:"inspector_id"`
+	Condition     string       `json:"condition"`
+	Notes         string       `json:"notes"`
+	DamageItems   []DamageItem `json:"damage_items" gorm:"serializer:json"`
+	DamageCharge  float64      `json:"damage_charge"`
+	MaintenanceID *uint        `json:"maintenance_id"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+func (i *ReturnInspection) Validate() error {
+	if !IsValidCondition(i.Condition) {
+		return fmt.Errorf("%w: condition must be one of Good, Damaged or Needs Maintenance", ErrInvalidInspection)
+	}
+	if i.DamageCharge < 0 {
+		return fmt.Errorf("%w: damage charge cannot be negative", ErrInvalidInspection)
+	}
+	for _, item := range i.DamageItems {
+		if item.Cost < 0 {
+			return fmt.Errorf("%w: damage item cost cannot be negative", ErrInvalidInspection)
+		}
+	}
+	return nil
+}
+
+// DamageItemsTotal is the sum of the costs of the reported damage items.
+func (i *ReturnInspection) DamageItemsTotal() float64 {
+	var total float64
+	for _, item := range i.DamageItems {
+		total += item.Cost
+	}
+	return roundCents(total)
+}
+
+// Summary describes the inspection in one line, for maintenance tickets and
+// deposit forfeits.
+func (i *ReturnInspection) Summary() string {
+	parts := []string{fmt.Sprintf("Return inspection of rental #%d: %s", i.RentalID, i.Condition)}
+	if i.Notes != "" {
+		parts = append(parts, i.Notes)
+	}
+	for _, item := range i.DamageItems {
+		parts = append(parts, item.Description)
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,187 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	InvoiceIssued = "issued"
+	InvoicePaid   = "paid"
+	InvoiceVoid   = "void"
+
+	InvoiceLineRental   = "rental"
+	InvoiceLineRounding = "rounding"
+	InvoiceLineLateFee  = "late_fee"
+	InvoiceLineDamage   = "damage"
+)
+
+var (
+	ErrInvoiceNotReady      = errors.New("only completed rentals and orders can be invoiced")
+	ErrInvalidInvoiceStatus = errors.New("invalid invoice status change")
+)
+
+// Invoices become paid and go back to issued as payments and refunds are
+// recorded; the only change made by hand is voiding an unpaid invoice.
+var invoiceTransitions = map[string][]string{
+	InvoiceIssued: {InvoiceVoid},
+}
+
+func CanTransitionInvoice(from, to string) bool {
+	for _, next := range invoiceTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Invoice bills a completed standalone rental or a completed order. The number
+// comes from a gap-free sequence. A rental or order has at most one invoice
+// that is not void, so a voided invoice can be reissued under a new number.
+// DepositApplied is what was already kept from the rentals' deposits for the
+// late fees and damage billed here; it is credited against the total.
+type Invoice struct {
+	ID             uint          `json:"id" gorm:"primaryKey"`
+	Number         string        `json:"number"`
+	UserID         uint          `json:"user_id"`
+	RentalID       *uint         `json:"rental_id"`
+	OrderID        *uint         `json:"order_id"`
+	Status         string        `json:"status" gorm:"not null;default:'issued'"`
+	IssueDate      time.Time     `json:"issue_date"`
+	PeriodStart    time.Time     `json:"period_start"`
+	PeriodEnd      time.Time     `json:"period_end"`
+	Subtotal       float64       `json:"subtotal"`
+	TaxTotal       float64       `json:"tax_total"`
+	DepositApplied float64       `json:"deposit_applied"`
+	Total          float64       `json:"total"`
+	AmountPaid     float64       `json:"amount_paid"`
+	Lines          []InvoiceLine `json:"lines" gorm:"foreignKey:InvoiceID"`
+	Taxes          []InvoiceTax  `json:"taxes" gorm:"foreignKey:InvoiceID"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
+
+type InvoiceLine struct {
+	ID          uint    `json:"id" gorm:"primaryKey"`
+	InvoiceID   uint    `json:"invoice_id"`
+	Position    int     `json:"position"`
+	Kind        string  `json:"kind"`
+	Description string  `json:"description"`
+	Quantity    int     `json:"quantity"`
+	UnitPrice   float64 `json:"unit_price"`
+	Amount      float64 `json:"amount"`
+}
+
+type InvoiceTax struct {
+	ID        uint    `json:"id" gorm:"primaryKey"`
+	InvoiceID uint    `json:"invoice_id"`
+	Name      string  `json:"name"`
+	Rate      float64 `json:"rate"`
+	Amount    float64 `json:"amount"`
+}
+
+type TaxRate struct {
+	Name string
+	Rate float64
+}
+
+// DefaultTaxRates reads INVOICE_TAX_RATES, a comma separated list of
+// name=rate pairs such as "PPN=0.11". Without it invoices carry 11% PPN.
+// Set it to "none" to issue invoices without tax.
+func DefaultTaxRates() []TaxRate {
+	value := os.Getenv("INVOICE_TAX_RATES")
+	switch value {
+	case "":
+		return []TaxRate{{Name: "PPN", Rate: 0.11}}
+	case "none":
+		return nil
+	}
+
+	var rates []TaxRate
+	for _, pair := range strings.Split(value, ",") {
+		name, rate, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(rate, 64)
+		if err != nil || parsed < 0 {
+			continue
+		}
+		rates = append(rates, TaxRate{Name: strings.TrimSpace(name), Rate: parsed})
+	}
+	return rates
+}
+
+// InvoiceNumber formats a sequence value as an invoice number.
+func InvoiceNumber(sequence int) string {
+	return fmt.Sprintf("INV-%06d", sequence)
+}
+
+// AddRental appends the lines for one returned rental: its pricing tiers, any
+// rounding, the late fee and the damage charge.
+func (inv *Invoice) AddRental(rental *RentalHistory, machineName string) {
+	if rental.CostBreakdown != nil && len(rental.CostBreakdown.Lines) > 0 {
+		for _, line := range rental.CostBreakdown.Lines {
+			inv.addLine(InvoiceLineRental, fmt.Sprintf("%s, %s rate", machineName, line.Tier), line.Units, line.Rate, line.Amount)
+		}
+		if rounding := rental.CostBreakdown.Rounding; rounding != 0 {
+			inv.addLine(InvoiceLineRounding, fmt.Sprintf("%s, rounding", machineName), 1, rounding, rounding)
+		}
+	} else {
+		inv.addLine(InvoiceLineRental, machineName, 1, rental.TotalCost, rental.TotalCost)
+	}
+
+	if rental.LateFee > 0 {
+		days := "days"
+		if rental.OverdueDays == 1 {
+			days = "day"
+		}
+		inv.addLine(InvoiceLineLateFee, fmt.Sprintf("%s, %d %s late", machineName, rental.OverdueDays, days), 1, rental.LateFee, rental.LateFee)
+	}
+	if rental.DamageCharge > 0 {
+		inv.addLine(InvoiceLineDamage, fmt.Sprintf("%s, damage", machineName), 1, rental.DamageCharge, rental.DamageCharge)
+	}
+
+	if inv.PeriodStart.IsZero() || rental.RentalDate.Before(inv.PeriodStart) {
+		inv.PeriodStart = rental.RentalDate
+	}
+	if rental.ReturnDate != nil && rental.ReturnDate.After(inv.PeriodEnd) {
+		inv.PeriodEnd = *rental.ReturnDate
+	}
+}
+
+func (inv *Invoice) addLine(kind, description string, quantity int, unitPrice, amount float64) {
+	inv.Lines = append(inv.Lines, InvoiceLine{
+		Position:    len(inv.Lines) + 1,
+		Kind:        kind,
+		Description: description,
+		Quantity:    quantity,
+		UnitPrice:   unitPrice,
+		Amount:      amount,
+	})
+}
+
+// ApplyTaxes sums the lines and adds one tax line per rate on the subtotal.
+// The deposit applied is taken off after tax, the way a payment would be.
+func (inv *Invoice) ApplyTaxes(rates []TaxRate) {
+	var subtotal float64
+	for _, line := range inv.Lines {
+		subtotal += line.Amount
+	}
+	inv.Subtotal = roundCents(subtotal)
+
+	inv.Taxes = nil
+	var taxTotal float64
+	for _, rate := range rates {
+		amount := roundCents(inv.Subtotal * rate.Rate)
+		inv.Taxes = append(inv.Taxes, InvoiceTax{Name: rate.Name, Rate: rate.Rate, Amount: amount})
+		taxTotal += amount
+	}
+	inv.TaxTotal = roundCents(taxTotal)
+	inv.Total = roundCents(inv.Subtotal + inv.TaxTotal - inv.DepositApplied)
+}
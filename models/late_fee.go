@@ -0,0 +1,59 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+var ErrInvalidLateFeePolicy = errors.New("invalid late fee policy")
+
+// LateFeePolicy prices each started day a rental is kept past its due date at
+// DailyFee plus RateMultiplier times the machine's daily rate. A policy
+// applies either to one machine or to a whole category; machine policies win.
+type LateFeePolicy struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	MachineID      *uint     `json:"machine_id"`
+	Category       *string   `json:"category"`
+	DailyFee       float64   `json:"daily_fee"`
+	RateMultiplier float64   `json:"rate_multiplier"`
+	GraceHours     int       `json:"grace_hours"`
+	MaxFee         float64   `json:"max_fee"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// DefaultLateFeePolicy applies when neither the machine nor its category has
+// a policy: one day's rent for every day late.
+func DefaultLateFeePolicy() LateFeePolicy {
+	return LateFeePolicy{RateMultiplier: 1}
+}
+
+func (p *LateFeePolicy) Validate() error {
+	hasMachine := p.MachineID != nil
+	hasCategory := p.Category != nil && *p.Category != ""
+	if hasMachine == hasCategory {
+		return fmt.Errorf("%w: set exactly one of machine_id or category", ErrInvalidLateFeePolicy)
+	}
+	if p.DailyFee < 0 || p.RateMultiplier < 0 || p.GraceHours < 0 || p.MaxFee < 0 {
+		return fmt.Errorf("%w: amounts cannot be negative", ErrInvalidLateFeePolicy)
+	}
+	return nil
+}
+
+// LateFee returns the number of overdue days and the fee owed for a rental due
+// at dueDate and still out (or returned) at "at".
+func (p *LateFeePolicy) LateFee(dailyRate float64, dueDate, at time.Time) (int, float64) {
+	late := at.Sub(dueDate) - time.Duration(p.GraceHours)*time.Hour
+	if late <= 0 {
+		return 0, 0
+	}
+
+	days := int(math.Ceil(late.Hours() / 24))
+	fee := float64(days) * (p.DailyFee + p.RateMultiplier*dailyRate)
+	if p.MaxFee > 0 && fee > p.MaxFee {
+		fee = p.MaxFee
+	}
+	return days, math.Round(fee*100) / 100
+}
@@ -2,61 +2,64 @@ package models
 
 import (
 	"errors"
-	"log"
+	"rental-api/pricing"
 	"time"
 
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-var DB *gorm.DB
-
-func ConnectDatabase() error {
-	var err error
-	DB, err = gorm.Open(sqlite.Open("rental.db"), &gorm.Config{})
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func CloseDatabase() {
-	sqlDB, err := DB.DB()
-	if err != nil {
-		log.Println("Error getting SQL DB instance:", err)
-		return
-	}
-	sqlDB.Close()
-}
+var (
+	ErrMachineNotFound = errors.New("machine not found")
+	ErrOutOfStock      = errors.New("machine is out of stock")
+	ErrAlreadyReturned = errors.New("rental has already been returned")
+	ErrInvalidRole     = errors.New("invalid role")
+	ErrInvalidLogin    = errors.New("invalid credentials")
+)
 
 type Maintenance struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	MachineID uint      `json:"machine_id"`
-	Issue     string    `json:"issue"`
-	Fixed     bool      `json:"fixed"`
-	FixedAt   time.Time `json:"fixed_at"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	MachineID uint       `json:"machine_id"`
+	Issue     string     `json:"issue"`
+	Fixed     bool       `json:"fixed"`
+	FixedAt   *time.Time `json:"fixed_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
 }
 
 type RentalHistory struct {
-	ID         uint       `json:"id" gorm:"primaryKey"`
-	UserID     uint       `json:"user_id"`
-	MachineID  uint       `json:"machine_id"`
-	RentalDate time.Time  `json:"rental_date"`
-	ReturnDate *time.Time `json:"return_date"`
-	CreatedAt  time.Time  `json:"created_at"`
-	UpdatedAt  time.Time  `json:"updated_at"`
+	ID            uint               `json:"id" gorm:"primaryKey"`
+	UserID        uint               `json:"user_id"`
+	MachineID     uint               `json:"machine_id"`
+	OrderID       *uint              `json:"order_id"`
+	OrderLineID   *uint              `json:"order_line_id"`
+	RentalDate    time.Time          `json:"rental_date"`
+	DueDate       *time.Time         `json:"due_date"`
+	ReturnDate    *time.Time         `json:"return_date"`
+	Status        string             `json:"status" gorm:"not null;default:'requested'"`
+	TotalCost     float64            `json:"total_cost" gorm:"not null;default:0"`
+	LateFee       float64            `json:"late_fee" gorm:"not null;default:0"`
+	OverdueDays   int                `json:"overdue_days" gorm:"not null;default:0"`
+	DamageCharge  float64            `json:"damage_charge" gorm:"not null;default:0"`
+	CostBreakdown *pricing.Breakdown `json:"cost_breakdown" gorm:"serializer:json"`
+	CreatedAt     time.Time          `json:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at"`
 }
 
 type Review struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	UserID    uint      `json:"user_id"`
-	MachineID uint      `json:"machine_id"`
-	Rating    int       `json:"rating"`
-	Comment   string    `json:"comment"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID               uint           `json:"id" gorm:"primaryKey"`
+	UserID           uint           `json:"user_id"`
+	MachineID        uint           `json:"machine_id"`
+	RentalID         *uint          `json:"rental_id" gorm:"unique"`
+	Rating           int            `json:"rating" gorm:"not null;check:rating BETWEEN 1 AND 5"`
+	Comment          string         `json:"comment"`
+	Status           string         `json:"status" gorm:"not null;default:'approved'"`
+	ModerationReason string         `json:"moderation_reason"`
+	ModeratedBy      *uint          `json:"moderated_by"`
+	ModeratedAt      *time.Time     `json:"moderated_at"`
+	Reports          []ReviewReport `json:"reports,omitempty" gorm:"foreignKey:ReviewID"`
+	Reply            *ReviewReply   `json:"reply,omitempty" gorm:"foreignKey:ReviewID"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
 }
 
 type User struct {
@@ -65,209 +68,82 @@ type User struct {
 	Password  string    `json:"password"`
 	FirstName string    `json:"first_name"`
 	LastName  string    `json:"last_name"`
+	Role      string    `json:"role" gorm:"not null;default:'customer';check:role IN ('customer', 'staff', 'technician', 'admin')"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
-type MesinBor struct {
-	gorm.Model
-	Name              string  `gorm:"not null;unique" json:"name"`
-	StockAvailability int     `gorm:"not null;default:0" json:"stock_availability"`
-	RentalCosts       float64 `gorm:"not null;default:0" json:"rental_costs"`
-	Category          string  `gorm:"default:'Uncategorized'" json:"category"`
-	Description       string  `gorm:"size:255" json:"description"`
-	Brand             string  `gorm:"size:100" json:"brand"`
-	Condition         string  `gorm:"default:'Good';check:condition IN ('Good', 'Damaged', 'Needs Maintenance')" json:"condition"`
-}
-
-func GetMachines() ([]MesinBor, error) {
-	var machines []MesinBor
-	if err := DB.Find(&machines).Error; err != nil {
-		return nil, err
-	}
-	return machines, nil
-}
-
-func GetMachineByID(id int) (*MesinBor, error) {
-	var machine MesinBor
-	if err := DB.First(&machine, id).Error; err != nil {
-		return nil, err
-	}
-	return &machine, nil
-}
-
-func CreateMachine(machine *MesinBor) error {
-	if err := DB.Create(machine).Error; err != nil {
-		return err
-	}
-	return nil
-}
-
-func UpdateMachine(id int, updatedMachine *MesinBor) error {
-	var machine MesinBor
-	if err := DB.First(&machine, id).Error; err != nil {
-		return err
-	}
-
-	if err := DB.Model(&machine).Updates(updatedMachine).Error; err != nil {
-		return err
-	}
-	return nil
-}
-
-func DeleteMachine(id int) error {
-	if err := DB.Delete(&MesinBor{}, id).Error; err != nil {
-		return err
-	}
-	return nil
-}
-
-func CreateMaintenance(maintenance *Maintenance) error {
-	if err := DB.Create(&maintenance).Error; err != nil {
-		return err
-	}
-	return nil
-}
-
-func GetMaintenanceByID(id int) (*Maintenance, error) {
-	var maintenance Maintenance
-	if err := DB.First(&maintenance, id).Error; err != nil {
-		return nil, err
-	}
-	return &maintenance, nil
-}
-
-func GetAllMaintenance() ([]Maintenance, error) {
-	var records []Maintenance
-	if err := DB.Find(&records).Error; err != nil {
-		return nil, err
-	}
-	return records, nil
-}
-
-func CreateRental(rental *RentalHistory) error {
-	if err := DB.Create(&rental).Error; err != nil {
-		return err
-	}
-	return nil
-}
-
-func GetRentalByID(id int) (*RentalHistory, error) {
-	var rental RentalHistory
-	if err := DB.First(&rental, id).Error; err != nil {
-		return nil, err
-	}
-	return &rental, nil
-}
-
-func GetAllRentals() ([]RentalHistory, error) {
-	var rentals []RentalHistory
-	if err := DB.Find(&rentals).Error; err != nil {
-		return nil, err
-	}
-	return rentals, nil
-}
-
-func MarkAsReturned(id int, returnDate *time.Time) error {
-	var rental RentalHistory
-	if err := DB.First(&rental, id).Error; err != nil {
-		return err
-	}
-
-	rental.ReturnDate = returnDate
-	if err := DB.Save(&rental).Error; err != nil {
-		return err
-	}
-	return nil
-}
-
-func CreateReview(review *Review) error {
-	if err := DB.Create(&review).Error; err != nil {
-		return err
-	}
-	return nil
-}
-
-func GetReviewByID(id int) (*Review, error) {
-	var review Review
-	if err := DB.First(&review, id).Error; err != nil {
-		return nil, err
-	}
-	return &review, nil
-}
-
-func GetAllReviews() ([]Review, error) {
-	var reviews []Review
-	if err := DB.Find(&reviews).Error; err != nil {
-		return nil, err
-	}
-	return reviews, nil
-}
-
-func DeleteReview(id int) error {
-	if err := DB.Delete(&Review{}, id).Error; err != nil {
-		return err
-	}
-	return nil
-}
-
-func CreateUser(user *User) error {
-	if err := DB.Create(&user).Error; err != nil {
-		return err
-	}
-	return nil
-}
-
-func GetUserByID(id int) (*User, error) {
-	var user User
-	if err := DB.First(&user, id).Error; err != nil {
-		return nil, err
-	}
-	return &user, nil
-}
-
-func UpdateUser(id int, user *User) error {
-	var existingUser User
-	if err := DB.First(&existingUser, id).Error; err != nil {
-		return err
-	}
-
-	existingUser.Email = user.Email
-	existingUser.Password = user.Password
-	existingUser.FirstName = user.FirstName
-	existingUser.LastName = user.LastName
+const (
+	RoleCustomer   = "customer"
+	RoleStaff      = "staff"
+	RoleTechnician = "technician"
+	RoleAdmin      = "admin"
+)
 
-	if err := DB.Save(&existingUser).Error; err != nil {
-		return err
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleCustomer, RoleStaff, RoleTechnician, RoleAdmin:
+		return true
 	}
-	return nil
+	return false
 }
 
-func DeleteUser(id int) error {
-	if err := DB.Delete(&User{}, id).Error; err != nil {
-		return err
+// HasRole reports whether the user holds one of the given roles. Admins hold
+// every role.
+func (u *User) HasRole(roles ...string) bool {
+	if u.Role == RoleAdmin {
+		return true
 	}
-	return nil
-}
-
-func CheckUserExists(email string) (bool, error) {
-	var user User
-	if err := DB.Where("email = ?", email).First(&user).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return false, nil
+	for _, role := range roles {
+		if u.Role == role {
+			return true
 		}
-		return false, err
 	}
-	return true, nil
+	return false
 }
 
-func AuthenticateUser(email, password string) (*User, error) {
-	var user User
-	if err := DB.Where("email = ? AND password = ?", email, password).First(&user).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("invalid credentials")
-		}
-		return nil, err
-	}
-	return &user, nil
+type MesinBor struct {
+	gorm.Model
+	Name              string        `gorm:"not null;unique" json:"name"`
+	StockAvailability int           `gorm:"not null;default:0" json:"stock_availability"`
+	RentalCosts       float64       `gorm:"not null;default:0" json:"rental_costs"`
+	WeeklyRate        float64       `gorm:"not null;default:0" json:"weekly_rate"`
+	MonthlyRate       float64       `gorm:"not null;default:0" json:"monthly_rate"`
+	MinRentalDays     int           `gorm:"not null;default:1" json:"min_rental_days"`
+	DepositAmount     float64       `gorm:"not null;default:0" json:"deposit_amount"`
+	DepositRate       float64       `gorm:"not null;default:0" json:"deposit_rate"`
+	Category          string        `gorm:"default:'Uncategorized'" json:"category"`
+	Description       string        `gorm:"size:255" json:"description"`
+	Brand             string        `gorm:"size:100" json:"brand"`
+	Condition         string        `gorm:"default:'Good';check:condition IN ('Good', 'Damaged', 'Needs Maintenance')" json:"condition"`
+	Ratings           RatingSummary `gorm:"embedded" json:"-"`
+}
+
+func (m *MesinBor) Rates() pricing.Rates {
+	return pricing.Rates{
+		Daily:         m.RentalCosts,
+		Weekly:        m.WeeklyRate,
+		Monthly:       m.MonthlyRate,
+		MinRentalDays: m.MinRentalDays,
+	}
+}
+
+// PriceRental sets the rental's cost from the machine rates. Returned rentals
+// are priced on the actual period, open ones on the due date or the minimum
+// charge period.
+func PriceRental(machine *MesinBor, rental *RentalHistory) {
+	rules := pricing.DefaultRules()
+
+	var breakdown pricing.Breakdown
+	switch {
+	case rental.ReturnDate != nil:
+		breakdown = pricing.Quote(machine.Rates(), rental.RentalDate, *rental.ReturnDate, rules)
+	case rental.DueDate != nil:
+		breakdown = pricing.Quote(machine.Rates(), rental.RentalDate, *rental.DueDate, rules)
+	default:
+		breakdown = pricing.QuoteDays(machine.Rates(), 1, rules)
+	}
+
+	rental.TotalCost = breakdown.Total
+	rental.CostBreakdown = &breakdown
 }
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+const (
+	NotificationReviewReply       = "review_reply"
+	NotificationReviewReplyEdited = "review_reply_edited"
+)
+
+// Notification is an in-app message for a user. ResourceType and ResourceID
+// point at what it is about, when there is such a thing.
+type Notification struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	UserID       uint       `json:"user_id"`
+	Kind         string     `json:"kind"`
+	Message      string     `json:"message"`
+	ResourceType string     `json:"resource_type"`
+	ResourceID   *uint      `json:"resource_id"`
+	ReadAt       *time.Time `json:"read_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
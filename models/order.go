@@ -0,0 +1,85 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	OrderOpen              = "open"
+	OrderPartiallyReturned = "partially_returned"
+	OrderReturned          = "returned"
+	OrderCancelled         = "cancelled"
+)
+
+var (
+	ErrEmptyOrder           = errors.New("an order needs at least one line item")
+	ErrInvalidQuantity      = errors.New("quantity must be at least 1")
+	ErrOrderClosed          = errors.New("order has no open rentals")
+	ErrOrderLineNotFound    = errors.New("order line not found")
+	ErrReturnQuantityTooBig = errors.New("return quantity exceeds the units still out")
+)
+
+// RentalOrder groups the rentals taken in one visit. Each unit of each line is
+// a RentalHistory of its own, so stock, pricing, deposits and late fees work
+// per unit; the order sums them up and returns or cancels them together.
+type RentalOrder struct {
+	ID         uint              `json:"id" gorm:"primaryKey"`
+	UserID     uint              `json:"user_id"`
+	RentalDate time.Time         `json:"rental_date"`
+	DueDate    *time.Time        `json:"due_date"`
+	Status     string            `json:"status" gorm:"not null;default:'open'"`
+	TotalCost  float64           `json:"total_cost" gorm:"not null;default:0"`
+	Notes      string            `json:"notes"`
+	Lines      []RentalOrderLine `json:"lines" gorm:"foreignKey:OrderID"`
+	Rentals    []RentalHistory   `json:"rentals" gorm:"foreignKey:OrderID"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+type RentalOrderLine struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	OrderID   uint      `json:"order_id"`
+	MachineID uint      `json:"machine_id"`
+	Quantity  int       `json:"quantity"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OrderLineError says which line of an order could not be reserved.
+type OrderLineError struct {
+	MachineID uint
+	Err       error
+}
+
+func (e *OrderLineError) Error() string {
+	return fmt.Sprintf("machine %d: %v", e.MachineID, e.Err)
+}
+
+func (e *OrderLineError) Unwrap() error {
+	return e.Err
+}
+
+// OrderStatusFor derives an order's status from the statuses of its rentals.
+func OrderStatusFor(rentals []RentalHistory) string {
+	cancelled, returned := 0, 0
+	for _, rental := range rentals {
+		switch rental.Status {
+		case RentalCancelled:
+			cancelled++
+		case RentalReturned:
+			returned++
+		}
+	}
+
+	switch {
+	case len(rentals) > 0 && cancelled == len(rentals):
+		return OrderCancelled
+	case len(rentals) > 0 && cancelled+returned == len(rentals):
+		return OrderReturned
+	case returned > 0:
+		return OrderPartiallyReturned
+	default:
+		return OrderOpen
+	}
+}
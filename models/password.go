@@ -0,0 +1,45 @@
+package models
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dummyHash is compared against when no user matches the email, so a failed
+// lookup costs about as much as a failed password check.
+var dummyHash = []byte("$2a$10$oE5OnrbTHNptII56LENDMe1xIpF9QIco0N9ehUbCGEmyTm1bgoE/q")
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func IsPasswordHash(password string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(password, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckPassword reports whether password matches the stored value and whether
+// the stored value is still plaintext and should be upgraded to a hash.
+func CheckPassword(stored, password string) (ok bool, needsUpgrade bool) {
+	if IsPasswordHash(stored) {
+		return bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) == nil, false
+	}
+	SimulatePasswordCheck(password)
+	return subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1, true
+}
+
+// SimulatePasswordCheck spends the same time as a failed bcrypt comparison. It
+// is used when no user matches the email.
+func SimulatePasswordCheck(password string) {
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
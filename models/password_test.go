@@ -0,0 +1,60 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("s3cret")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	if hash == "s3cret" || !IsPasswordHash(hash) {
+		t.Fatalf("expected a bcrypt hash, got %q", hash)
+	}
+
+	again, err := HashPassword("s3cret")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	if again == hash {
+		t.Fatal("expected every hash to be salted differently")
+	}
+
+	if ok, needsUpgrade := CheckPassword(hash, "s3cret"); !ok || needsUpgrade {
+		t.Fatalf("expected the password to match its hash without upgrade, got ok=%v upgrade=%v", ok, needsUpgrade)
+	}
+	if ok, _ := CheckPassword(hash, "wrong"); ok {
+		t.Fatal("expected a wrong password not to match")
+	}
+}
+
+func TestIsPasswordHash(t *testing.T) {
+	tests := []struct {
+		stored string
+		hashed bool
+	}{
+		{stored: "$2a$10$" + strings.Repeat("a", 53), hashed: true},
+		{stored: "$2b$10$" + strings.Repeat("a", 53), hashed: true},
+		{stored: "$2y$10$" + strings.Repeat("a", 53), hashed: true},
+		{stored: "password123", hashed: false},
+		{stored: "$1$md5crypt", hashed: false},
+		{stored: "", hashed: false},
+	}
+
+	for _, tt := range tests {
+		if got := IsPasswordHash(tt.stored); got != tt.hashed {
+			t.Errorf("IsPasswordHash(%q) = %v, want %v", tt.stored, got, tt.hashed)
+		}
+	}
+}
+
+func TestCheckPasswordLegacyPlaintext(t *testing.T) {
+	if ok, needsUpgrade := CheckPassword("password123", "password123"); !ok || !needsUpgrade {
+		t.Fatalf("expected a plaintext match flagged for upgrade, got ok=%v upgrade=%v", ok, needsUpgrade)
+	}
+	if ok, _ := CheckPassword("password123", "password12"); ok {
+		t.Fatal("expected a wrong password not to match the plaintext")
+	}
+}
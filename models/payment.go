@@ -0,0 +1,72 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	PaymentKindPayment = "payment"
+	PaymentKindRefund  = "refund"
+
+	PaymentPending   = "pending"
+	PaymentSucceeded = "succeeded"
+	PaymentFailed    = "failed"
+)
+
+var (
+	ErrInvalidPaymentAmount = errors.New("payment amount must be positive")
+	ErrOverpayment          = errors.New("payment exceeds the invoice balance")
+	ErrRefundTooLarge       = errors.New("refund exceeds what is left of the payment")
+	ErrRefundTarget         = errors.New("only succeeded payments of the invoice can be refunded")
+	ErrInvoiceVoid          = errors.New("invoice is void")
+	ErrPaymentNotPending    = errors.New("payment is no longer pending")
+)
+
+// Payment is money received against an invoice, or a refund of such a
+// payment. ExternalID is the gateway's reference for it, when the gateway has
+// one.
+type Payment struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	InvoiceID  uint      `json:"invoice_id"`
+	UserID     uint      `json:"user_id"`
+	Kind       string    `json:"kind"`
+	Method     string    `json:"method"`
+	Status     string    `json:"status" gorm:"not null;default:'pending'"`
+	Amount     float64   `json:"amount"`
+	ExternalID *string   `json:"external_id"`
+	Reference  string    `json:"reference"`
+	Note       string    `json:"note"`
+	RefundOfID *uint     `json:"refund_of_id"`
+	ActorID    *uint     `json:"actor_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// WebhookEvent is a verified gateway callback reporting the final status of a
+// payment. EventID is unique per gateway, so redelivered callbacks are
+// recognised and skipped.
+type WebhookEvent struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	Gateway    string    `json:"gateway"`
+	EventID    string    `json:"event_id"`
+	ExternalID string    `json:"external_id"`
+	Status     string    `json:"status"`
+	Payload    string    `json:"payload"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AccountBalance sums what a user has been invoiced and what they have paid.
+// A negative Outstanding means the user is in credit.
+type AccountBalance struct {
+	UserID      uint    `json:"user_id"`
+	Invoiced    float64 `json:"invoiced"`
+	Paid        float64 `json:"paid"`
+	Refunded    float64 `json:"refunded"`
+	Outstanding float64 `json:"outstanding"`
+}
+
+// BalanceDue is what is still owed on the invoice.
+func (inv *Invoice) BalanceDue() float64 {
+	return roundCents(inv.Total - inv.AmountPaid)
+}
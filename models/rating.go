@@ -0,0 +1,73 @@
+package models
+
+import "math"
+
+// The Bayesian score treats every machine as if it already had
+// RatingPriorWeight reviews averaging RatingPriorMean, so a single five-star
+// review does not outrank a long record of fours. A fixed prior keeps the
+// score a function of the machine's own reviews, so it can be stored and
+// indexed for sorting.
+const (
+	RatingPriorMean   = 3.0
+	RatingPriorWeight = 5
+)
+
+// RatingSummary holds a machine's review aggregates. It is kept up to date as
+// reviews are written, edited and deleted rather than computed on read.
+type RatingSummary struct {
+	Count   int     `gorm:"column:rating_count;not null;default:0"`
+	Sum     int     `gorm:"column:rating_sum;not null;default:0"`
+	Count1  int     `gorm:"column:rating_1_count;not null;default:0"`
+	Count2  int     `gorm:"column:rating_2_count;not null;default:0"`
+	Count3  int     `gorm:"column:rating_3_count;not null;default:0"`
+	Count4  int     `gorm:"column:rating_4_count;not null;default:0"`
+	Count5  int     `gorm:"column:rating_5_count;not null;default:0"`
+	Average float64 `gorm:"column:rating_average;not null;default:0"`
+	Score   float64 `gorm:"column:rating_score;not null;default:3"`
+}
+
+// RatingColumns are the columns RatingSummary is stored in.
+var RatingColumns = []string{
+	"rating_count", "rating_sum",
+	"rating_1_count", "rating_2_count", "rating_3_count", "rating_4_count", "rating_5_count",
+	"rating_average", "rating_score",
+}
+
+// Add counts a review with the given rating; a negative delta removes it.
+func (s *RatingSummary) Add(rating int, delta int) {
+	switch rating {
+	case 1:
+		s.Count1 += delta
+	case 2:
+		s.Count2 += delta
+	case 3:
+		s.Count3 += delta
+	case 4:
+		s.Count4 += delta
+	case 5:
+		s.Count5 += delta
+	default:
+		return
+	}
+	s.Count += delta
+	s.Sum += rating * delta
+
+	s.Average = 0
+	if s.Count > 0 {
+		s.Average = roundRating(float64(s.Sum) / float64(s.Count))
+	}
+	s.Score = BayesianScore(s.Sum, s.Count)
+}
+
+// Distribution returns the number of reviews for each rating from 1 to 5.
+func (s *RatingSummary) Distribution() map[int]int {
+	return map[int]int{1: s.Count1, 2: s.Count2, 3: s.Count3, 4: s.Count4, 5: s.Count5}
+}
+
+func BayesianScore(sum, count int) float64 {
+	return roundRating((RatingPriorWeight*RatingPriorMean + float64(sum)) / float64(RatingPriorWeight+count))
+}
+
+func roundRating(value float64) float64 {
+	return math.Round(value*10000) / 10000
+}
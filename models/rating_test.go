@@ -0,0 +1,71 @@
+package models
+
+import "testing"
+
+func TestBayesianScore(t *testing.T) {
+	tests := []struct {
+		name  string
+		sum   int
+		count int
+		score float64
+	}{
+		{name: "no reviews is the prior", sum: 0, count: 0, score: RatingPriorMean},
+		{name: "one five", sum: 5, count: 1, score: 3.3333},
+		{name: "one one", sum: 1, count: 1, score: 2.6667},
+		{name: "reviews at the prior mean", sum: 30, count: 10, score: 3},
+		{name: "ten fives", sum: 50, count: 10, score: 4.3333},
+		{name: "twenty fours", sum: 80, count: 20, score: 3.8},
+		{name: "many fives approach five", sum: 5000, count: 1000, score: 4.99},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BayesianScore(tt.sum, tt.count); got != tt.score {
+				t.Errorf("expected score %v, got %v", tt.score, got)
+			}
+		})
+	}
+}
+
+func TestBayesianScoreRanksVolumeOverSingleReview(t *testing.T) {
+	single := BayesianScore(5, 1)
+	record := BayesianScore(4*20, 20)
+	if single >= record {
+		t.Errorf("expected twenty fours (%v) to outrank one five (%v)", record, single)
+	}
+}
+
+func TestRatingSummaryAdd(t *testing.T) {
+	var summary RatingSummary
+	for _, rating := range []int{5, 4, 4, 2} {
+		summary.Add(rating, 1)
+	}
+
+	want := RatingSummary{Count: 4, Sum: 15, Count2: 1, Count4: 2, Count5: 1, Average: 3.75, Score: 3.3333}
+	if summary != want {
+		t.Fatalf("expected %+v, got %+v", want, summary)
+	}
+	if got := summary.Distribution(); got[1] != 0 || got[2] != 1 || got[3] != 0 || got[4] != 2 || got[5] != 1 {
+		t.Errorf("unexpected distribution %v", got)
+	}
+
+	summary.Add(0, 1)
+	summary.Add(6, 1)
+	if summary != want {
+		t.Errorf("expected ratings outside 1-5 to be ignored, got %+v", summary)
+	}
+
+	summary.Add(4, -1)
+	want = RatingSummary{Count: 3, Sum: 11, Count2: 1, Count4: 1, Count5: 1, Average: 3.6667, Score: 3.25}
+	if summary != want {
+		t.Errorf("expected %+v after removing a four, got %+v", want, summary)
+	}
+
+	for _, rating := range []int{5, 4, 2} {
+		summary.Add(rating, -1)
+	}
+	want = RatingSummary{Score: RatingPriorMean}
+	if summary != want {
+		t.Errorf("expected an empty summary at the prior, got %+v", summary)
+	}
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrRentalNotExtendable = errors.New("rental cannot be extended")
+	ErrExtensionTooShort   = errors.New("new due date must be after the current due date")
+)
+
+// RentalCanExtend reports whether a rental in this status may still have its
+// due date pushed back. Overdue rentals have to be returned first.
+func RentalCanExtend(status string) bool {
+	switch status {
+	case RentalRequested, RentalConfirmed, RentalPickedUp, RentalActive:
+		return true
+	}
+	return false
+}
+
+// RentalExtension records one move of a rental's due date and what it added
+// to the rental's cost.
+type RentalExtension struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	RentalID        uint      `json:"rental_id"`
+	PreviousDueDate time.Time `json:"previous_due_date"`
+	NewDueDate      time.Time `json:"new_due_date"`
+	PreviousCost    float64   `json:"previous_cost"`
+	AdditionalCost  float64   `json:"additional_cost"`
+	ActorID         *uint     `json:"actor_id"`
+	Note            string    `json:"note"`
+	CreatedAt       time.Time `json:"created_at"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	RentalRequested = "requested"
+	RentalConfirmed = "confirmed"
+	RentalPickedUp  = "picked_up"
+	RentalActive    = "active"
+	RentalOverdue   = "overdue"
+	RentalReturned  = "returned"
+	RentalCancelled = "cancelled"
+)
+
+var ErrInvalidTransition = errors.New("invalid rental status transition")
+
+// rentalTransitions lists, for every status, the statuses a rental may move
+// to next. Returned and cancelled are final.
+var rentalTransitions = map[string][]string{
+	RentalRequested: {RentalConfirmed, RentalCancelled},
+	RentalConfirmed: {RentalPickedUp, RentalCancelled},
+	RentalPickedUp:  {RentalActive, RentalOverdue, RentalReturned},
+	RentalActive:    {RentalOverdue, RentalReturned},
+	RentalOverdue:   {RentalReturned},
+}
+
+func CanTransitionRental(from, to string) bool {
+	for _, next := range rentalTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// RentalHoldsStock reports whether a rental in this status keeps a unit of the
+// machine out of stock.
+func RentalHoldsStock(status string) bool {
+	return status != RentalReturned && status != RentalCancelled
+}
+
+type InvalidTransitionError struct {
+	From string
+	To   string
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("cannot move rental from %s to %s", e.From, e.To)
+}
+
+func (e *InvalidTransitionError) Unwrap() error {
+	return ErrInvalidTransition
+}
+
+// RentalStatusEvent records one status change. ActorID is nil for changes made
+// by the system rather than a user.
+type RentalStatusEvent struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	RentalID   uint      `json:"rental_id"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ActorID    *uint     `json:"actor_id"`
+	Note       string    `json:"note"`
+	CreatedAt  time.Time `json:"created_at"`
+}
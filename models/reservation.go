@@ -0,0 +1,115 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	ReservationBooked    = "booked"
+	ReservationCancelled = "cancelled"
+	ReservationFulfilled = "fulfilled"
+
+	BookingReservation = "reservation"
+	BookingRental      = "rental"
+)
+
+// OpenEnd stands in for the end of a rental that has no due date when it is
+// checked against bookings: it holds its unit until it is returned.
+var OpenEnd = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+var (
+	ErrBookingConflict     = errors.New("booking conflicts with existing bookings")
+	ErrReservationNotOpen  = errors.New("reservation is no longer booked")
+	ErrInvalidBookingRange = errors.New("end date must be after start date")
+)
+
+type Reservation struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id"`
+	MachineID uint      `json:"machine_id"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+	Status    string    `json:"status" gorm:"not null;default:'booked'"`
+	Notes     string    `json:"notes"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Booking is a reservation or an open rental occupying one unit of a machine.
+// A nil EndDate means the rental has no due date, or is past it, and occupies
+// the unit until it is returned.
+type Booking struct {
+	Type      string     `json:"type"`
+	ID        uint       `json:"id"`
+	UserID    uint       `json:"user_id"`
+	StartDate time.Time  `json:"start_date"`
+	EndDate   *time.Time `json:"end_date"`
+}
+
+func (b Booking) Overlaps(start, end time.Time) bool {
+	if !b.StartDate.Before(end) {
+		return false
+	}
+	return b.EndDate == nil || b.EndDate.After(start)
+}
+
+// BookingConflictError lists the bookings that, together with the units in
+// maintenance, leave no unit free for the requested period.
+type BookingConflictError struct {
+	Capacity    int
+	Maintenance int
+	Conflicts   []Booking
+}
+
+func (e *BookingConflictError) Error() string {
+	return fmt.Sprintf("%v: %d units, %d in maintenance, %d overlapping bookings", ErrBookingConflict, e.Capacity, e.Maintenance, len(e.Conflicts))
+}
+
+func (e *BookingConflictError) Unwrap() error {
+	return ErrBookingConflict
+}
+
+// PeakOverlap returns the highest number of bookings that are active at the
+// same moment within [start, end).
+func PeakOverlap(bookings []Booking, start, end time.Time) int {
+	type event struct {
+		at    time.Time
+		delta int
+	}
+
+	var events []event
+	for _, b := range bookings {
+		if !b.Overlaps(start, end) {
+			continue
+		}
+		from := b.StartDate
+		if from.Before(start) {
+			from = start
+		}
+		events = append(events, event{from, 1})
+		if b.EndDate != nil && b.EndDate.Before(end) {
+			events = append(events, event{*b.EndDate, -1})
+		}
+	}
+
+	// Ends sort before starts at the same instant so back-to-back bookings do
+	// not count as overlapping.
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].at.Equal(events[j].at) {
+			return events[i].delta < events[j].delta
+		}
+		return events[i].at.Before(events[j].at)
+	})
+
+	current, peak := 0, 0
+	for _, e := range events {
+		current += e.delta
+		if current > peak {
+			peak = current
+		}
+	}
+	return peak
+}
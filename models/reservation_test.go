@@ -0,0 +1,47 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeakOverlap(t *testing.T) {
+	day := func(n int) time.Time {
+		return time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, n)
+	}
+	booking := func(start, end int) Booking {
+		until := day(end)
+		return Booking{Type: BookingReservation, StartDate: day(start), EndDate: &until}
+	}
+	openEnded := func(start int) Booking {
+		return Booking{Type: BookingRental, StartDate: day(start)}
+	}
+
+	tests := []struct {
+		name     string
+		bookings []Booking
+		start    int
+		end      int
+		peak     int
+	}{
+		{name: "no bookings", start: 0, end: 5, peak: 0},
+		{name: "outside the window", bookings: []Booking{booking(0, 2), booking(8, 9)}, start: 2, end: 8, peak: 0},
+		{name: "back to back", bookings: []Booking{booking(0, 2), booking(2, 4), booking(4, 6)}, start: 0, end: 6, peak: 1},
+		{name: "overlapping", bookings: []Booking{booking(0, 3), booking(2, 5)}, start: 0, end: 6, peak: 2},
+		{name: "disjoint overlaps", bookings: []Booking{booking(0, 2), booking(1, 3), booking(4, 6), booking(5, 7)}, start: 0, end: 7, peak: 2},
+		{name: "nested", bookings: []Booking{booking(0, 10), booking(2, 8), booking(4, 6)}, start: 0, end: 10, peak: 3},
+		{name: "overlap outside the window", bookings: []Booking{booking(0, 3), booking(2, 5)}, start: 3, end: 6, peak: 1},
+		{name: "window inside a booking", bookings: []Booking{booking(0, 10)}, start: 4, end: 5, peak: 1},
+		{name: "open ended", bookings: []Booking{openEnded(0), booking(5, 6)}, start: 5, end: 8, peak: 2},
+		{name: "open ended after the window", bookings: []Booking{openEnded(8)}, start: 0, end: 8, peak: 0},
+		{name: "same period", bookings: []Booking{booking(1, 2), booking(1, 2), booking(1, 2)}, start: 0, end: 3, peak: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PeakOverlap(tt.bookings, day(tt.start), day(tt.end)); got != tt.peak {
+				t.Errorf("expected peak %d, got %d", tt.peak, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	MinRating = 1
+	MaxRating = 5
+
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
+var (
+	ErrInvalidRating       = errors.New("rating must be between 1 and 5")
+	ErrReviewNotAllowed    = errors.New("only the renter of a returned rental can review it")
+	ErrAlreadyReviewed     = errors.New("rental has already been reviewed")
+	ErrReviewNotPending    = errors.New("review is not awaiting moderation")
+	ErrReviewNotReportable = errors.New("review cannot be reported")
+	ErrAlreadyReported     = errors.New("review has already been reported by this user")
+	ErrEmptyReply          = errors.New("reply must not be empty")
+	ErrReviewNotPublished  = errors.New("review is not published")
+)
+
+// ReviewReport is a user's complaint about someone else's review. Reports stay
+// open until staff approve or reject the review.
+type ReviewReport struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ReviewID   uint      `json:"review_id"`
+	ReporterID uint      `json:"reporter_id"`
+	Reason     string    `json:"reason"`
+	Resolved   bool      `json:"resolved"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ReviewReply is the shop's public answer to a review. A review has at most
+// one; editing it changes the author to whoever edited it last.
+type ReviewReply struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ReviewID  uint      `json:"review_id"`
+	AuthorID  uint      `json:"author_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (r *Review) Validate() error {
+	if r.Rating < MinRating || r.Rating > MaxRating {
+		return ErrInvalidRating
+	}
+	return nil
+}
+
+// Verified reports whether the review is backed by a rental of the machine.
+// Only reviews written before rentals were required lack one.
+func (r *Review) Verified() bool {
+	return r.RentalID != nil
+}
+
+// Published reports whether the review is shown publicly and counted in the
+// machine's rating.
+func (r *Review) Published() bool {
+	return r.Status == ReviewApproved
+}
+
+// Screen sets the review's status from the blocked terms found in it. Clean
+// reviews keep their status, except that rejected reviews go back for
+// moderation when edited; new reviews start approved.
+func (r *Review) Screen(blocked []string) {
+	switch {
+	case len(blocked) > 0:
+		r.Status = ReviewPending
+		r.ModerationReason = "Contains blocked terms: " + strings.Join(blocked, ", ")
+	case r.Status == "":
+		r.Status = ReviewApproved
+	case r.Status == ReviewRejected:
+		r.Status = ReviewPending
+		r.ModerationReason = "Edited after rejection"
+	}
+}
@@ -0,0 +1,122 @@
+package moderation
+
+import (
+	"bufio"
+	"embed"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+//go:embed wordlists/*.txt
+var wordlists embed.FS
+
+const (
+	LanguageIndonesian = "id"
+	LanguageEnglish    = "en"
+)
+
+// Filter screens text against blocklists. Terms match whole words, case
+// insensitively; a term of several words matches them in sequence.
+type Filter struct {
+	terms    map[string]bool
+	maxWords int
+}
+
+func NewFilter(terms ...string) *Filter {
+	filter := &Filter{terms: make(map[string]bool, len(terms))}
+	for _, term := range terms {
+		normalized := normalize(term)
+		if normalized == "" {
+			continue
+		}
+		filter.terms[normalized] = true
+		if words := strings.Count(normalized, " ") + 1; words > filter.maxWords {
+			filter.maxWords = words
+		}
+	}
+	return filter
+}
+
+// DefaultFilter uses the built-in Indonesian and English blocklists.
+// REVIEW_BLOCKLIST_ID and REVIEW_BLOCKLIST_EN may name files that replace
+// them, and REVIEW_BLOCKLIST_EXTRA adds comma-separated terms to both.
+func DefaultFilter() (*Filter, error) {
+	var terms []string
+	for language, env := range map[string]string{
+		LanguageIndonesian: "REVIEW_BLOCKLIST_ID",
+		LanguageEnglish:    "REVIEW_BLOCKLIST_EN",
+	} {
+		var list io.ReadCloser
+		var err error
+		if path := os.Getenv(env); path != "" {
+			list, err = os.Open(path)
+		} else {
+			list, err = wordlists.Open("wordlists/" + language + ".txt")
+		}
+		if err != nil {
+			return nil, err
+		}
+		words, err := readTerms(list)
+		list.Close()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, words...)
+	}
+
+	if extra := os.Getenv("REVIEW_BLOCKLIST_EXTRA"); extra != "" {
+		terms = append(terms, strings.Split(extra, ",")...)
+	}
+	return NewFilter(terms...), nil
+}
+
+// readTerms reads one term per line, skipping blank lines and # comments.
+func readTerms(r io.Reader) ([]string, error) {
+	var terms []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		terms = append(terms, line)
+	}
+	return terms, scanner.Err()
+}
+
+// Check returns the blocked terms found in text, sorted.
+func (f *Filter) Check(text string) []string {
+	words := strings.Fields(normalize(text))
+
+	found := make(map[string]bool)
+	for i := range words {
+		phrase := ""
+		for j := i; j < len(words) && j-i < f.maxWords; j++ {
+			if j > i {
+				phrase += " "
+			}
+			phrase += words[j]
+			if f.terms[phrase] {
+				found[phrase] = true
+			}
+		}
+	}
+
+	matches := make([]string, 0, len(found))
+	for term := range found {
+		matches = append(matches, term)
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// normalize lowercases text and turns everything but letters and digits into
+// single spaces.
+func normalize(text string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
@@ -0,0 +1,51 @@
+package moderation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFilterCheck(t *testing.T) {
+	filter := NewFilter("scam", "rip off", "total rip off", "Dasar Penipu!", "  ", "b-word")
+
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "clean", text: "Great drill, would rent again.", want: []string{}},
+		{name: "single word", text: "This is a scam", want: []string{"scam"}},
+		{name: "case insensitive", text: "SCAM", want: []string{"scam"}},
+		{name: "whole words only", text: "The scamper mode is loud", want: []string{}},
+		{name: "punctuation around a word", text: "what a...scam!!!", want: []string{"scam"}},
+		{name: "phrase", text: "it was a rip off", want: []string{"rip off"}},
+		{name: "phrase across punctuation", text: "Rip, off!", want: []string{"rip off"}},
+		{name: "phrase across extra whitespace", text: "rip \n\t off", want: []string{"rip off"}},
+		{name: "phrase words apart", text: "rip it off", want: []string{}},
+		{name: "phrase split inside a word", text: "ripoff", want: []string{}},
+		{name: "longer phrase contains shorter", text: "A total rip-off.", want: []string{"rip off", "total rip off"}},
+		{name: "term normalized when added", text: "dasar penipu", want: []string{"dasar penipu"}},
+		{name: "punctuation inside a term", text: "the b word", want: []string{"b word"}},
+		{name: "several terms sorted", text: "scam... total rip off, SCAM", want: []string{"rip off", "scam", "total rip off"}},
+		{name: "phrase at end of text", text: "honestly rip", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filter.Check(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReadTermsSkipsCommentsAndBlankLines(t *testing.T) {
+	terms, err := readTerms(strings.NewReader("# comment\n\nscam\n  rip off  \n"))
+	if err != nil {
+		t.Fatalf("failed to read terms: %v", err)
+	}
+	if want := []string{"scam", "rip off"}; !reflect.DeepEqual(terms, want) {
+		t.Errorf("expected %q, got %q", want, terms)
+	}
+}
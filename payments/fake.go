@@ -0,0 +1,85 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"rental-api/models"
+	"sync/atomic"
+)
+
+const (
+	FakeGatewayName     = "fake"
+	FakeSignatureHeader = "X-Fake-Signature"
+)
+
+// FakeGateway behaves like an online provider without talking to one: charges
+// stay pending until a signed webhook settles them, refunds succeed at once.
+type FakeGateway struct {
+	secret string
+	seq    atomic.Uint64
+}
+
+// FakeWebhook is the body the fake gateway's webhooks carry.
+type FakeWebhook struct {
+	EventID   string `json:"event_id"`
+	PaymentID string `json:"payment_id"`
+	Status    string `json:"status"`
+}
+
+func NewFakeGateway(secret string) *FakeGateway {
+	return &FakeGateway{secret: secret}
+}
+
+func (g *FakeGateway) Name() string { return FakeGatewayName }
+
+func (g *FakeGateway) Offline() bool { return false }
+
+func (g *FakeGateway) Charge(ctx context.Context, req ChargeRequest) (Result, error) {
+	return Result{ExternalID: fmt.Sprintf("fake_pay_%d_%d", req.PaymentID, g.seq.Add(1)), Status: models.PaymentPending}, nil
+}
+
+func (g *FakeGateway) Refund(ctx context.Context, req RefundRequest) (Result, error) {
+	return Result{ExternalID: fmt.Sprintf("fake_ref_%d_%d", req.PaymentID, g.seq.Add(1)), Status: models.PaymentSucceeded}, nil
+}
+
+func (g *FakeGateway) ParseWebhook(header http.Header, body []byte) (*models.WebhookEvent, error) {
+	if !VerifySignature(g.secret, body, header.Get(FakeSignatureHeader)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var webhook FakeWebhook
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		return nil, ErrMalformedWebhookEvent
+	}
+	if webhook.EventID == "" || webhook.PaymentID == "" {
+		return nil, ErrMalformedWebhookEvent
+	}
+	switch webhook.Status {
+	case models.PaymentSucceeded, models.PaymentFailed:
+	default:
+		return nil, ErrMalformedWebhookEvent
+	}
+
+	return &models.WebhookEvent{
+		Gateway:    g.Name(),
+		EventID:    webhook.EventID,
+		ExternalID: webhook.PaymentID,
+		Status:     webhook.Status,
+		Payload:    string(body),
+	}, nil
+}
+
+// SignedWebhook builds a webhook body and its headers the way the fake
+// provider would send them.
+func (g *FakeGateway) SignedWebhook(webhook FakeWebhook) ([]byte, http.Header, error) {
+	body, err := json.Marshal(webhook)
+	if err != nil {
+		return nil, nil, err
+	}
+	header := http.Header{}
+	header.Set(FakeSignatureHeader, Sign(g.secret, body))
+	header.Set("Content-Type", "application/json")
+	return body, header, nil
+}
@@ -0,0 +1,72 @@
+package payments
+
+import (
+	"errors"
+	"net/http"
+	"rental-api/models"
+	"testing"
+)
+
+func TestFakeGatewayParseWebhook(t *testing.T) {
+	gateway := NewFakeGateway("test-secret")
+	webhook := FakeWebhook{EventID: "evt_1", PaymentID: "fake_pay_1_1", Status: models.PaymentSucceeded}
+
+	body, header, err := gateway.SignedWebhook(webhook)
+	if err != nil {
+		t.Fatalf("failed to sign webhook: %v", err)
+	}
+	event, err := gateway.ParseWebhook(header, body)
+	if err != nil {
+		t.Fatalf("expected a signed webhook to parse, got %v", err)
+	}
+	if event.Gateway != FakeGatewayName || event.EventID != "evt_1" || event.ExternalID != "fake_pay_1_1" || event.Status != models.PaymentSucceeded {
+		t.Errorf("unexpected event %+v", event)
+	}
+
+	other, otherHeader, err := NewFakeGateway("other-secret").SignedWebhook(webhook)
+	if err != nil {
+		t.Fatalf("failed to sign webhook: %v", err)
+	}
+
+	tampered := append([]byte{}, body...)
+	tampered[len(tampered)-3] = 'X'
+
+	cases := []struct {
+		name   string
+		header http.Header
+		body   []byte
+	}{
+		{"missing signature", http.Header{}, body},
+		{"signature is not hex", http.Header{FakeSignatureHeader: {"not-hex"}}, body},
+		{"signed with another secret", otherHeader, other},
+		{"body changed after signing", header, tampered},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := gateway.ParseWebhook(tc.header, tc.body); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("expected ErrInvalidSignature, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFakeGatewayRejectsMalformedWebhook(t *testing.T) {
+	gateway := NewFakeGateway("test-secret")
+
+	cases := map[string]FakeWebhook{
+		"missing event ID":   {PaymentID: "fake_pay_1_1", Status: models.PaymentSucceeded},
+		"missing payment ID": {EventID: "evt_1", Status: models.PaymentSucceeded},
+		"pending status":     {EventID: "evt_1", PaymentID: "fake_pay_1_1", Status: models.PaymentPending},
+	}
+	for name, webhook := range cases {
+		t.Run(name, func(t *testing.T) {
+			body, header, err := gateway.SignedWebhook(webhook)
+			if err != nil {
+				t.Fatalf("failed to sign webhook: %v", err)
+			}
+			if _, err := gateway.ParseWebhook(header, body); !errors.Is(err, ErrMalformedWebhookEvent) {
+				t.Errorf("expected ErrMalformedWebhookEvent, got %v", err)
+			}
+		})
+	}
+}
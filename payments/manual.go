@@ -0,0 +1,38 @@
+package payments
+
+import (
+	"context"
+	"net/http"
+	"rental-api/models"
+)
+
+const (
+	MethodCash         = "cash"
+	MethodBankTransfer = "bank_transfer"
+)
+
+// ManualGateway records money that changed hands outside the system. Every
+// charge and refund succeeds at once.
+type ManualGateway struct {
+	name string
+}
+
+func NewManualGateway(name string) *ManualGateway {
+	return &ManualGateway{name: name}
+}
+
+func (g *ManualGateway) Name() string { return g.name }
+
+func (g *ManualGateway) Offline() bool { return true }
+
+func (g *ManualGateway) Charge(ctx context.Context, req ChargeRequest) (Result, error) {
+	return Result{Status: models.PaymentSucceeded}, nil
+}
+
+func (g *ManualGateway) Refund(ctx context.Context, req RefundRequest) (Result, error) {
+	return Result{Status: models.PaymentSucceeded}, nil
+}
+
+func (g *ManualGateway) ParseWebhook(header http.Header, body []byte) (*models.WebhookEvent, error) {
+	return nil, ErrWebhooksNotSupported
+}
@@ -0,0 +1,199 @@
+package pricing
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	TierDaily   = "daily"
+	TierWeekly  = "weekly"
+	TierMonthly = "monthly"
+
+	daysPerWeek  = 7
+	daysPerMonth = 30
+
+	RoundUp      = "up"
+	RoundDown    = "down"
+	RoundNearest = "nearest"
+)
+
+// Rates are the per-machine prices. A zero weekly or monthly rate means the
+// tier is not offered for that machine.
+type Rates struct {
+	Daily         float64
+	Weekly        float64
+	Monthly       float64
+	MinRentalDays int
+}
+
+// Rules control how durations and amounts are rounded.
+type Rules struct {
+	GracePeriod  time.Duration
+	RoundTo      float64
+	RoundingMode string
+}
+
+type Line struct {
+	Tier   string  `json:"tier"`
+	Units  int     `json:"units"`
+	Rate   float64 `json:"rate"`
+	Amount float64 `json:"amount"`
+}
+
+type Breakdown struct {
+	RentedDays int     `json:"rented_days"`
+	BilledDays int     `json:"billed_days"`
+	MinDays    int     `json:"min_days"`
+	Lines      []Line  `json:"lines"`
+	Subtotal   float64 `json:"subtotal"`
+	Rounding   float64 `json:"rounding"`
+	Total      float64 `json:"total"`
+}
+
+func DefaultRules() Rules {
+	rules := Rules{
+		GracePeriod:  time.Hour,
+		RoundTo:      100,
+		RoundingMode: RoundNearest,
+	}
+
+	if grace, err := time.ParseDuration(os.Getenv("PRICING_GRACE_PERIOD")); err == nil && grace >= 0 {
+		rules.GracePeriod = grace
+	}
+	if roundTo, err := strconv.ParseFloat(os.Getenv("PRICING_ROUND_TO"), 64); err == nil && roundTo >= 0 {
+		rules.RoundTo = roundTo
+	}
+	switch mode := os.Getenv("PRICING_ROUNDING_MODE"); mode {
+	case RoundUp, RoundDown, RoundNearest:
+		rules.RoundingMode = mode
+	}
+
+	return rules
+}
+
+// RentedDays converts a rental period into whole days. Any started day counts
+// as a full day unless it falls within the grace period.
+func RentedDays(start, end time.Time, grace time.Duration) int {
+	elapsed := end.Sub(start) - grace
+	if elapsed <= 0 {
+		return 1
+	}
+	days := int(math.Ceil(elapsed.Hours() / 24))
+	if days < 1 {
+		days = 1
+	}
+	return days
+}
+
+func Quote(rates Rates, start, end time.Time, rules Rules) Breakdown {
+	return QuoteDays(rates, RentedDays(start, end, rules.GracePeriod), rules)
+}
+
+// QuoteDays prices a number of rented days, applying the minimum charge period
+// and picking the cheapest combination of daily, weekly and monthly tiers.
+func QuoteDays(rates Rates, days int, rules Rules) Breakdown {
+	minDays := rates.MinRentalDays
+	if minDays < 1 {
+		minDays = 1
+	}
+	billed := days
+	if billed < minDays {
+		billed = minDays
+	}
+
+	lines := cheapestLines(rates, billed)
+	var subtotal float64
+	for _, line := range lines {
+		subtotal += line.Amount
+	}
+	subtotal = roundCents(subtotal)
+	total := roundAmount(subtotal, rules)
+
+	return Breakdown{
+		RentedDays: days,
+		BilledDays: billed,
+		MinDays:    minDays,
+		Lines:      lines,
+		Subtotal:   subtotal,
+		Rounding:   roundCents(total - subtotal),
+		Total:      total,
+	}
+}
+
+type tier struct {
+	name string
+	days int
+	rate float64
+}
+
+func cheapestLines(rates Rates, days int) []Line {
+	tiers := []tier{{TierDaily, 1, rates.Daily}}
+	if rates.Weekly > 0 {
+		tiers = append(tiers, tier{TierWeekly, daysPerWeek, rates.Weekly})
+	}
+	if rates.Monthly > 0 {
+		tiers = append(tiers, tier{TierMonthly, daysPerMonth, rates.Monthly})
+	}
+
+	// cost[n] is the cheapest way to cover at least n days. A longer tier may
+	// cover more days than needed when that is still cheaper.
+	cost := make([]float64, days+1)
+	choice := make([]int, days+1)
+	for n := 1; n <= days; n++ {
+		cost[n] = math.Inf(1)
+		for i, t := range tiers {
+			prev := n - t.days
+			if prev < 0 {
+				prev = 0
+			}
+			if c := cost[prev] + t.rate; c < cost[n] {
+				cost[n] = c
+				choice[n] = i
+			}
+		}
+	}
+
+	units := make([]int, len(tiers))
+	for n := days; n > 0; {
+		i := choice[n]
+		units[i]++
+		n -= tiers[i].days
+	}
+
+	var lines []Line
+	for i := len(tiers) - 1; i >= 0; i-- {
+		if units[i] == 0 {
+			continue
+		}
+		lines = append(lines, Line{
+			Tier:   tiers[i].name,
+			Units:  units[i],
+			Rate:   tiers[i].rate,
+			Amount: roundCents(float64(units[i]) * tiers[i].rate),
+		})
+	}
+	return lines
+}
+
+func roundAmount(amount float64, rules Rules) float64 {
+	if rules.RoundTo <= 0 {
+		return amount
+	}
+	steps := amount / rules.RoundTo
+	switch rules.RoundingMode {
+	case RoundUp:
+		steps = math.Ceil(steps - 1e-9)
+	case RoundDown:
+		steps = math.Floor(steps + 1e-9)
+	default:
+		steps = math.Round(steps)
+	}
+	return roundCents(steps * rules.RoundTo)
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
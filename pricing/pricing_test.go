@@ -0,0 +1,198 @@
+package pricing
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestQuoteDaysTiers(t *testing.T) {
+	all := Rates{Daily: 100, Weekly: 500, Monthly: 1800}
+
+	tests := []struct {
+		name  string
+		rates Rates
+		days  int
+		lines []Line
+		total float64
+	}{
+		{
+			name:  "single day",
+			rates: all,
+			days:  1,
+			lines: []Line{{Tier: TierDaily, Units: 1, Rate: 100, Amount: 100}},
+			total: 100,
+		},
+		{
+			name:  "days cheaper than a week",
+			rates: all,
+			days:  4,
+			lines: []Line{{Tier: TierDaily, Units: 4, Rate: 100, Amount: 400}},
+			total: 400,
+		},
+		{
+			name:  "week covers more days when cheaper",
+			rates: all,
+			days:  6,
+			lines: []Line{{Tier: TierWeekly, Units: 1, Rate: 500, Amount: 500}},
+			total: 500,
+		},
+		{
+			name:  "week plus days",
+			rates: all,
+			days:  9,
+			lines: []Line{
+				{Tier: TierWeekly, Units: 1, Rate: 500, Amount: 500},
+				{Tier: TierDaily, Units: 2, Rate: 100, Amount: 200},
+			},
+			total: 700,
+		},
+		{
+			name:  "month covers more days when cheaper",
+			rates: all,
+			days:  26,
+			lines: []Line{{Tier: TierMonthly, Units: 1, Rate: 1800, Amount: 1800}},
+			total: 1800,
+		},
+		{
+			name:  "month plus days",
+			rates: all,
+			days:  33,
+			lines: []Line{
+				{Tier: TierMonthly, Units: 1, Rate: 1800, Amount: 1800},
+				{Tier: TierDaily, Units: 3, Rate: 100, Amount: 300},
+			},
+			total: 2100,
+		},
+		{
+			name:  "month, week and days",
+			rates: all,
+			days:  39,
+			lines: []Line{
+				{Tier: TierMonthly, Units: 1, Rate: 1800, Amount: 1800},
+				{Tier: TierWeekly, Units: 1, Rate: 500, Amount: 500},
+				{Tier: TierDaily, Units: 2, Rate: 100, Amount: 200},
+			},
+			total: 2500,
+		},
+		{
+			name:  "tiers not offered",
+			rates: Rates{Daily: 100},
+			days:  9,
+			lines: []Line{{Tier: TierDaily, Units: 9, Rate: 100, Amount: 900}},
+			total: 900,
+		},
+		{
+			name:  "weeks without a monthly rate",
+			rates: Rates{Daily: 100, Weekly: 500},
+			days:  30,
+			lines: []Line{
+				{Tier: TierWeekly, Units: 4, Rate: 500, Amount: 2000},
+				{Tier: TierDaily, Units: 2, Rate: 100, Amount: 200},
+			},
+			total: 2200,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := QuoteDays(tt.rates, tt.days, Rules{})
+			if !reflect.DeepEqual(got.Lines, tt.lines) {
+				t.Errorf("expected lines %+v, got %+v", tt.lines, got.Lines)
+			}
+			if got.Subtotal != tt.total || got.Total != tt.total {
+				t.Errorf("expected subtotal and total %v, got %v and %v", tt.total, got.Subtotal, got.Total)
+			}
+		})
+	}
+}
+
+func TestQuoteDaysMinimumDays(t *testing.T) {
+	tests := []struct {
+		name    string
+		rates   Rates
+		days    int
+		billed  int
+		minDays int
+		total   float64
+	}{
+		{name: "no minimum", rates: Rates{Daily: 100}, days: 1, billed: 1, minDays: 1, total: 100},
+		{name: "negative minimum", rates: Rates{Daily: 100, MinRentalDays: -2}, days: 1, billed: 1, minDays: 1, total: 100},
+		{name: "below minimum", rates: Rates{Daily: 100, MinRentalDays: 3}, days: 1, billed: 3, minDays: 3, total: 300},
+		{name: "at minimum", rates: Rates{Daily: 100, MinRentalDays: 3}, days: 3, billed: 3, minDays: 3, total: 300},
+		{name: "above minimum", rates: Rates{Daily: 100, MinRentalDays: 3}, days: 5, billed: 5, minDays: 3, total: 500},
+		{name: "minimum priced as a week", rates: Rates{Daily: 100, Weekly: 500, MinRentalDays: 7}, days: 2, billed: 7, minDays: 7, total: 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := QuoteDays(tt.rates, tt.days, Rules{})
+			if got.RentedDays != tt.days || got.BilledDays != tt.billed || got.MinDays != tt.minDays {
+				t.Errorf("expected %d rented, %d billed and %d minimum days, got %d, %d and %d",
+					tt.days, tt.billed, tt.minDays, got.RentedDays, got.BilledDays, got.MinDays)
+			}
+			if got.Total != tt.total {
+				t.Errorf("expected total %v, got %v", tt.total, got.Total)
+			}
+		})
+	}
+}
+
+func TestQuoteDaysRounding(t *testing.T) {
+	tests := []struct {
+		name     string
+		daily    float64
+		rules    Rules
+		total    float64
+		rounding float64
+	}{
+		{name: "no rounding", daily: 1234.56, rules: Rules{RoundTo: 0, RoundingMode: RoundUp}, total: 1234.56, rounding: 0},
+		{name: "nearest rounds down", daily: 1234.56, rules: Rules{RoundTo: 100, RoundingMode: RoundNearest}, total: 1200, rounding: -34.56},
+		{name: "nearest rounds half up", daily: 1250, rules: Rules{RoundTo: 100, RoundingMode: RoundNearest}, total: 1300, rounding: 50},
+		{name: "unknown mode rounds to nearest", daily: 1260, rules: Rules{RoundTo: 100}, total: 1300, rounding: 40},
+		{name: "up", daily: 1234.56, rules: Rules{RoundTo: 100, RoundingMode: RoundUp}, total: 1300, rounding: 65.44},
+		{name: "up keeps exact multiples", daily: 1200, rules: Rules{RoundTo: 100, RoundingMode: RoundUp}, total: 1200, rounding: 0},
+		{name: "down", daily: 1299.99, rules: Rules{RoundTo: 100, RoundingMode: RoundDown}, total: 1200, rounding: -99.99},
+		{name: "down keeps exact multiples", daily: 1300, rules: Rules{RoundTo: 100, RoundingMode: RoundDown}, total: 1300, rounding: 0},
+		{name: "fractional step", daily: 10.12, rules: Rules{RoundTo: 0.5, RoundingMode: RoundUp}, total: 10.5, rounding: 0.38},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := QuoteDays(Rates{Daily: tt.daily}, 1, tt.rules)
+			if got.Subtotal != tt.daily {
+				t.Errorf("expected subtotal %v, got %v", tt.daily, got.Subtotal)
+			}
+			if got.Total != tt.total || got.Rounding != tt.rounding {
+				t.Errorf("expected total %v with rounding %v, got %v with %v", tt.total, tt.rounding, got.Total, got.Rounding)
+			}
+		})
+	}
+}
+
+func TestRentedDays(t *testing.T) {
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		end   time.Time
+		grace time.Duration
+		days  int
+	}{
+		{name: "returned at once", end: start, grace: 0, days: 1},
+		{name: "returned before start", end: start.Add(-time.Hour), grace: 0, days: 1},
+		{name: "exactly one day", end: start.Add(24 * time.Hour), grace: 0, days: 1},
+		{name: "started second day", end: start.Add(25 * time.Hour), grace: 0, days: 2},
+		{name: "within grace period", end: start.Add(25 * time.Hour), grace: time.Hour, days: 1},
+		{name: "past grace period", end: start.Add(25*time.Hour + time.Minute), grace: time.Hour, days: 2},
+		{name: "three days", end: start.Add(72 * time.Hour), grace: time.Hour, days: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RentedDays(start, tt.end, tt.grace); got != tt.days {
+				t.Errorf("expected %d days, got %d", tt.days, got)
+			}
+		})
+	}
+}
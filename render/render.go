@@ -0,0 +1,113 @@
+package render
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"math"
+	"os"
+	"rental-api/models"
+	"strings"
+	texttemplate "text/template"
+	"time"
+	"unicode/utf8"
+)
+
+//go:embed templates/*
+var templates embed.FS
+
+// InvoiceView is what the invoice templates are rendered from.
+type InvoiceView struct {
+	Invoice  *models.Invoice
+	Customer *models.User
+	Company  Company
+}
+
+type Company struct {
+	Name    string
+	Address string
+	TaxID   string
+}
+
+// DefaultCompany reads the seller details printed on invoices from
+// COMPANY_NAME, COMPANY_ADDRESS and COMPANY_TAX_ID.
+func DefaultCompany() Company {
+	name := os.Getenv("COMPANY_NAME")
+	if name == "" {
+		name = "Rental Mesin Bor"
+	}
+	return Company{
+		Name:    name,
+		Address: os.Getenv("COMPANY_ADDRESS"),
+		TaxID:   os.Getenv("COMPANY_TAX_ID"),
+	}
+}
+
+var funcs = map[string]interface{}{
+	"money": Money,
+	"date":  func(t time.Time) string { return t.Format("02 Jan 2006") },
+	"percent": func(rate float64) string {
+		return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", rate*100), "0"), ".") + "%"
+	},
+	"neg":    func(amount float64) float64 { return -amount },
+	"left":   padLeft,
+	"right":  padRight,
+	"repeat": strings.Repeat,
+}
+
+var (
+	htmlInvoice = htmltemplate.Must(htmltemplate.New("invoice.html.tmpl").Funcs(funcs).ParseFS(templates, "templates/invoice.html.tmpl"))
+	textInvoice = texttemplate.Must(texttemplate.New("invoice.pdf.tmpl").Funcs(funcs).ParseFS(templates, "templates/invoice.pdf.tmpl"))
+)
+
+func InvoiceHTML(w io.Writer, view InvoiceView) error {
+	return htmlInvoice.Execute(w, view)
+}
+
+// InvoicePDF lays the invoice out with the fixed-width PDF template and writes
+// it as a PDF document.
+func InvoicePDF(w io.Writer, view InvoiceView) error {
+	var text bytes.Buffer
+	if err := textInvoice.Execute(&text, view); err != nil {
+		return err
+	}
+	return writePDF(w, strings.Split(strings.TrimRight(text.String(), "\n"), "\n"))
+}
+
+// Money formats an amount in rupiah, e.g. "Rp 1.250.000,00".
+func Money(amount float64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	cents := int64(math.Round(amount * 100))
+	whole := fmt.Sprintf("%d", cents/100)
+
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte('.')
+		}
+		grouped.WriteRune(digit)
+	}
+	return fmt.Sprintf("%sRp %s,%02d", sign, grouped.String(), cents%100)
+}
+
+// padLeft left-aligns s in a column of the given width, cutting it if needed.
+func padLeft(width int, s string) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return string([]rune(s)[:width])
+}
+
+// padRight right-aligns s in a column of the given width.
+func padRight(width int, s string) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return strings.Repeat(" ", width-n) + s
+	}
+	return s
+}
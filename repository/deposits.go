@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"rental-api/models"
+
+	"gorm.io/gorm"
+)
+
+type gormDepositRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormDepositRepository) Balance(ctx context.Context, userID uint) (*models.DepositBalance, error) {
+	totals, err := depositTotals(conn(ctx, r.db).Where("user_id = ?", userID))
+	if err != nil {
+		return nil, err
+	}
+	balance := models.NewDepositBalance(userID, totals)
+	return &balance, nil
+}
+
+func (r *gormDepositRepository) Entries(ctx context.Context, userID uint) ([]models.DepositEntry, error) {
+	var entries []models.DepositEntry
+	if err := conn(ctx, r.db).Where("user_id = ?", userID).Order("created_at, id").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Credit records money the user paid into their deposit account.
+func (r *gormDepositRepository) Credit(ctx context.Context, userID uint, amount float64, reason string, actorID *uint) (*models.DepositEntry, error) {
+	if amount <= 0 {
+		return nil, models.ErrInvalidDepositAmount
+	}
+
+	entry := models.DepositEntry{UserID: userID, Kind: models.DepositCredit, Amount: amount, Reason: reason, ActorID: actorID}
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		if err := tx.First(&models.User{}, userID).Error; err != nil {
+			return err
+		}
+		return tx.Create(&entry).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Refund pays money back out of the user's deposit account. Only the
+// available part can be refunded.
+func (r *gormDepositRepository) Refund(ctx context.Context, userID uint, amount float64, reason string, actorID *uint) (*models.DepositEntry, error) {
+	if amount <= 0 {
+		return nil, models.ErrInvalidDepositAmount
+	}
+
+	entry := models.DepositEntry{UserID: userID, Kind: models.DepositRefund, Amount: amount, Reason: reason, ActorID: actorID}
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		// Lock the user so two refunds cannot both spend the same balance.
+		result := tx.Model(&models.User{}).Where("id = ?", userID).
+			UpdateColumn("updated_at", gorm.Expr("updated_at"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		totals, err := depositTotals(tx.Where("user_id = ?", userID))
+		if err != nil {
+			return err
+		}
+		if balance := models.NewDepositBalance(userID, totals); amount > balance.Available {
+			return models.ErrInsufficientDeposit
+		}
+		return tx.Create(&entry).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func depositTotals(query *gorm.DB) (map[string]float64, error) {
+	var rows []struct {
+		Kind  string
+		Total float64
+	}
+	if err := query.Model(&models.DepositEntry{}).
+		Select("kind, COALESCE(SUM(amount), 0) AS total").
+		Group("kind").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	totals := make(map[string]float64, len(rows))
+	for _, row := range rows {
+		totals[row.Kind] = row.Total
+	}
+	return totals, nil
+}
+
+// holdDeposit puts the machine's deposit for the rental on hold. The hold is
+// sized on the rental's price when it is created and topped up by
+// topUpDeposit when an extension makes it dearer.
+func holdDeposit(tx *gorm.DB, rental *models.RentalHistory, machine *models.MesinBor) error {
+	amount := machine.DepositFor(rental.TotalCost)
+	if amount <= 0 {
+		return nil
+	}
+
+	rentalID := rental.ID
+	actorID := rental.UserID
+	return tx.Create(&models.DepositEntry{
+		UserID:   rental.UserID,
+		RentalID: &rentalID,
+		Kind:     models.DepositHold,
+		Amount:   amount,
+		Reason:   fmt.Sprintf("Deposit for rental #%d", rental.ID),
+		ActorID:  &actorID,
+	}).Error
+}
+
+// topUpDeposit holds more of the deposit when the rental's price has gone up
+// since the hold was taken, so the hold matches what the machine asks for at
+// the new price. The hold is never lowered before the rental is settled.
+func topUpDeposit(tx *gorm.DB, rental *models.RentalHistory, machine *models.MesinBor, actorID *uint) error {
+	totals, err := depositTotals(tx.Where("rental_id = ?", rental.ID))
+	if err != nil {
+		return err
+	}
+	held := models.NewDepositBalance(rental.UserID, totals).Held
+
+	extra := math.Round((machine.DepositFor(rental.TotalCost)-held)*100) / 100
+	if extra <= 0 {
+		return nil
+	}
+
+	rentalID := rental.ID
+	return tx.Create(&models.DepositEntry{
+		UserID:   rental.UserID,
+		RentalID: &rentalID,
+		Kind:     models.DepositHold,
+		Amount:   extra,
+		Reason:   fmt.Sprintf("Deposit top-up for extending rental #%d", rental.ID),
+		ActorID:  actorID,
+	}).Error
+}
+
+// settleDeposit closes the rental's hold: each charge is forfeited from it in
+// turn, up to what is still held, and the rest is released back to the user.
+func settleDeposit(tx *gorm.DB, rental *models.RentalHistory, charges []models.DepositCharge, actorID *uint) error {
+	totals, err := depositTotals(tx.Where("rental_id = ?", rental.ID))
+	if err != nil {
+		return err
+	}
+	held := models.NewDepositBalance(rental.UserID, totals).Held
+
+	rentalID := rental.ID
+	for _, charge := range charges {
+		amount := math.Min(charge.Amount, held)
+		if amount <= 0 {
+			continue
+		}
+		if err := tx.Create(&models.DepositEntry{
+			UserID:   rental.UserID,
+			RentalID: &rentalID,
+			Kind:     models.DepositForfeit,
+			Amount:   amount,
+			Reason:   charge.Reason,
+			ActorID:  actorID,
+		}).Error; err != nil {
+			return err
+		}
+		held = math.Round((held-amount)*100) / 100
+	}
+
+	if held <= 0 {
+		return nil
+	}
+	return tx.Create(&models.DepositEntry{
+		UserID:   rental.UserID,
+		RentalID: &rentalID,
+		Kind:     models.DepositRelease,
+		Amount:   held,
+		Reason:   fmt.Sprintf("Deposit released for rental #%d", rental.ID),
+		ActorID:  actorID,
+	}).Error
+}
@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"rental-api/models"
+	"slices"
+	"testing"
+	"time"
+)
+
+func createDepositTestUser(t *testing.T, repos *Repositories, email string) *models.User {
+	t.Helper()
+
+	user := models.User{Email: email, Password: "secret123"}
+	if err := repos.Users.Create(context.Background(), &user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return &user
+}
+
+func checkDeposit(t *testing.T, repos *Repositories, userID uint, total, held, available float64) {
+	t.Helper()
+
+	balance, err := repos.Deposits.Balance(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("failed to get deposit balance: %v", err)
+	}
+	if balance.Total != total || balance.Held != held || balance.Available != available {
+		t.Errorf("expected total %v, held %v and available %v, got %v, %v and %v",
+			total, held, available, balance.Total, balance.Held, balance.Available)
+	}
+}
+
+func depositKinds(t *testing.T, repos *Repositories, userID uint) []string {
+	t.Helper()
+
+	entries, err := repos.Deposits.Entries(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("failed to list deposit entries: %v", err)
+	}
+	kinds := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		kinds = append(kinds, entry.Kind)
+	}
+	return kinds
+}
+
+func TestDepositHoldAndRefund(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+	user := createDepositTestUser(t, repos, "hold@example.com")
+
+	machine := models.MesinBor{Name: "Bosch GBH 4-32", StockAvailability: 2, RentalCosts: 50000, DepositAmount: 100000, DepositRate: 0.1}
+	if err := repos.Machines.Create(ctx, &machine); err != nil {
+		t.Fatalf("failed to create machine: %v", err)
+	}
+
+	if _, err := repos.Deposits.Credit(ctx, user.ID, 0, "", nil); !errors.Is(err, models.ErrInvalidDepositAmount) {
+		t.Errorf("expected ErrInvalidDepositAmount for a zero credit, got %v", err)
+	}
+	if _, err := repos.Deposits.Credit(ctx, user.ID, 300000, "Cash", nil); err != nil {
+		t.Fatalf("failed to credit deposit: %v", err)
+	}
+	checkDeposit(t, repos, user.ID, 300000, 0, 300000)
+
+	due := time.Now().Add(2 * 24 * time.Hour)
+	rental := models.RentalHistory{UserID: user.ID, MachineID: machine.ID, RentalDate: time.Now(), DueDate: &due}
+	if err := repos.Rentals.Create(ctx, &rental); err != nil {
+		t.Fatalf("failed to create rental: %v", err)
+	}
+	// 100000 fixed plus 10% of two days at 50000.
+	checkDeposit(t, repos, user.ID, 300000, 110000, 190000)
+
+	if _, err := repos.Deposits.Refund(ctx, user.ID, 190001, "", nil); !errors.Is(err, models.ErrInsufficientDeposit) {
+		t.Errorf("expected ErrInsufficientDeposit for more than is available, got %v", err)
+	}
+	if _, err := repos.Deposits.Refund(ctx, user.ID, 190000, "Payout", nil); err != nil {
+		t.Fatalf("failed to refund deposit: %v", err)
+	}
+	checkDeposit(t, repos, user.ID, 110000, 110000, 0)
+
+	// A hold may exceed what the user has paid in; the rest is owed.
+	other := models.RentalHistory{UserID: user.ID, MachineID: machine.ID, RentalDate: time.Now(), DueDate: &due}
+	if err := repos.Rentals.Create(ctx, &other); err != nil {
+		t.Fatalf("failed to create rental: %v", err)
+	}
+	checkDeposit(t, repos, user.ID, 110000, 220000, -110000)
+
+	if _, err := repos.Rentals.Transition(ctx, int(other.ID), models.RentalCancelled, nil, ""); err != nil {
+		t.Fatalf("failed to cancel rental: %v", err)
+	}
+	checkDeposit(t, repos, user.ID, 110000, 110000, 0)
+
+	want := []string{models.DepositCredit, models.DepositHold, models.DepositRefund, models.DepositHold, models.DepositRelease}
+	if got := depositKinds(t, repos, user.ID); !slices.Equal(got, want) {
+		t.Errorf("expected ledger %v, got %v", want, got)
+	}
+}
+
+func TestDepositSettledOnReturn(t *testing.T) {
+	tests := []struct {
+		name      string
+		deposit   float64
+		lateBy    time.Duration
+		forfeited float64
+		released  float64
+	}{
+		{name: "on time releases the hold", deposit: 150000, lateBy: 0, forfeited: 0, released: 150000},
+		{name: "late fee forfeited from the hold", deposit: 150000, lateBy: 25 * time.Hour, forfeited: 100000, released: 50000},
+		{name: "forfeit limited to the hold", deposit: 60000, lateBy: 25 * time.Hour, forfeited: 60000, released: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repos := setupTestRepositories(t)
+			ctx := context.Background()
+			user := createDepositTestUser(t, repos, "late@example.com")
+
+			machine := models.MesinBor{Name: "Hilti TE 60", StockAvailability: 1, RentalCosts: 50000, DepositAmount: tt.deposit}
+			if err := repos.Machines.Create(ctx, &machine); err != nil {
+				t.Fatalf("failed to create machine: %v", err)
+			}
+			if _, err := repos.Deposits.Credit(ctx, user.ID, 200000, "Cash", nil); err != nil {
+				t.Fatalf("failed to credit deposit: %v", err)
+			}
+
+			due := time.Now().Add(-3 * 24 * time.Hour)
+			rental := models.RentalHistory{UserID: user.ID, MachineID: machine.ID, RentalDate: due.Add(-48 * time.Hour), DueDate: &due}
+			if err := repos.Rentals.Create(ctx, &rental); err != nil {
+				t.Fatalf("failed to create rental: %v", err)
+			}
+			for _, status := range []string{models.RentalConfirmed, models.RentalPickedUp} {
+				if _, err := repos.Rentals.Transition(ctx, int(rental.ID), status, nil, ""); err != nil {
+					t.Fatalf("failed to move rental to %s: %v", status, err)
+				}
+			}
+			checkDeposit(t, repos, user.ID, 200000, tt.deposit, 200000-tt.deposit)
+
+			returned, err := repos.Rentals.MarkAsReturned(ctx, int(rental.ID), due.Add(tt.lateBy), nil, nil)
+			if err != nil {
+				t.Fatalf("failed to return rental: %v", err)
+			}
+			if tt.lateBy > 0 && returned.LateFee != 100000 {
+				t.Fatalf("expected a late fee of 100000, got %v", returned.LateFee)
+			}
+
+			checkDeposit(t, repos, user.ID, 200000-tt.forfeited, 0, 200000-tt.forfeited)
+
+			entries, err := repos.Deposits.Entries(ctx, user.ID)
+			if err != nil {
+				t.Fatalf("failed to list deposit entries: %v", err)
+			}
+			var forfeited, released float64
+			for _, entry := range entries {
+				switch entry.Kind {
+				case models.DepositForfeit:
+					forfeited += entry.Amount
+				case models.DepositRelease:
+					released += entry.Amount
+				}
+			}
+			if forfeited != tt.forfeited || released != tt.released {
+				t.Errorf("expected %v forfeited and %v released, got %v and %v", tt.forfeited, tt.released, forfeited, released)
+			}
+		})
+	}
+}
+
+func TestExtendTopsUpDeposit(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+	user := createDepositTestUser(t, repos, "extend@example.com")
+
+	machine := models.MesinBor{Name: "Makita HR4013", StockAvailability: 1, RentalCosts: 50000, DepositAmount: 100000, DepositRate: 0.1}
+	if err := repos.Machines.Create(ctx, &machine); err != nil {
+		t.Fatalf("failed to create machine: %v", err)
+	}
+
+	start := time.Now()
+	due := start.Add(2 * 24 * time.Hour)
+	rental := models.RentalHistory{UserID: user.ID, MachineID: machine.ID, RentalDate: start, DueDate: &due}
+	if err := repos.Rentals.Create(ctx, &rental); err != nil {
+		t.Fatalf("failed to create rental: %v", err)
+	}
+	checkDeposit(t, repos, user.ID, 0, 110000, -110000)
+
+	if _, _, err := repos.Rentals.Extend(ctx, int(rental.ID), start.Add(4*24*time.Hour), nil, ""); err != nil {
+		t.Fatalf("failed to extend rental: %v", err)
+	}
+	// The rental now costs 200000, so the hold grows by 10% of the extra 100000.
+	checkDeposit(t, repos, user.ID, 0, 120000, -120000)
+
+	if _, err := repos.Rentals.Transition(ctx, int(rental.ID), models.RentalCancelled, nil, ""); err != nil {
+		t.Fatalf("failed to cancel rental: %v", err)
+	}
+	checkDeposit(t, repos, user.ID, 0, 0, 0)
+
+	want := []string{models.DepositHold, models.DepositHold, models.DepositRelease}
+	if got := depositKinds(t, repos, user.ID); !slices.Equal(got, want) {
+		t.Errorf("expected ledger %v, got %v", want, got)
+	}
+}
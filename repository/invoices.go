@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"rental-api/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const invoiceSequence = "invoice"
+
+type gormInvoiceRepository struct {
+	db *gorm.DB
+}
+
+// GenerateForRental returns the invoice of a returned standalone rental,
+// issuing it first if needed. created reports whether it was issued now.
+func (r *gormInvoiceRepository) GenerateForRental(ctx context.Context, rentalID uint) (*models.Invoice, bool, error) {
+	var invoice *models.Invoice
+	var created bool
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		var rental models.RentalHistory
+		if err := tx.First(&rental, rentalID).Error; err != nil {
+			return err
+		}
+		var err error
+		invoice, created, err = invoiceRental(tx, &rental)
+		return err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return invoice, created, nil
+}
+
+// GenerateForOrder returns the invoice of a completed order, issuing it first
+// if needed. created reports whether it was issued now.
+func (r *gormInvoiceRepository) GenerateForOrder(ctx context.Context, orderID uint) (*models.Invoice, bool, error) {
+	var invoice *models.Invoice
+	var created bool
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		var err error
+		invoice, created, err = invoiceOrder(tx, orderID)
+		return err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return invoice, created, nil
+}
+
+func (r *gormInvoiceRepository) GetByID(ctx context.Context, id int) (*models.Invoice, error) {
+	var invoice models.Invoice
+	if err := preloadInvoice(conn(ctx, r.db)).First(&invoice, id).Error; err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+func (r *gormInvoiceRepository) List(ctx context.Context) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+	if err := conn(ctx, r.db).Order("id").Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
+
+func (r *gormInvoiceRepository) ListByUser(ctx context.Context, userID uint) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+	if err := conn(ctx, r.db).Where("user_id = ?", userID).Order("id").Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
+
+// UpdateStatus voids an issued invoice. Invoices become paid through
+// payments, and one that has taken or is awaiting money cannot be voided.
+func (r *gormInvoiceRepository) UpdateStatus(ctx context.Context, id int, status string) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		if err := tx.First(&invoice, id).Error; err != nil {
+			return err
+		}
+		if !models.CanTransitionInvoice(invoice.Status, status) || invoice.AmountPaid > 0 {
+			return models.ErrInvalidInvoiceStatus
+		}
+		var pending int64
+		if err := tx.Model(&models.Payment{}).
+			Where("invoice_id = ? AND status = ?", invoice.ID, models.PaymentPending).
+			Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending > 0 {
+			return models.ErrInvalidInvoiceStatus
+		}
+
+		result := tx.Model(&invoice).Where("status = ?", invoice.Status).Update("status", status)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return models.ErrInvalidInvoiceStatus
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(ctx, id)
+}
+
+func preloadInvoice(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("Taxes", func(db *gorm.DB) *gorm.DB { return db.Order("id") })
+}
+
+// invoiceRental issues the invoice for a returned rental that is not part of
+// an order, or returns the one already issued. A void invoice does not count,
+// so calling it again after voiding reissues the invoice. created reports
+// whether the invoice was issued by this call.
+func invoiceRental(tx *gorm.DB, rental *models.RentalHistory) (*models.Invoice, bool, error) {
+	if rental.Status != models.RentalReturned || rental.OrderID != nil {
+		return nil, false, models.ErrInvoiceNotReady
+	}
+
+	// Touch the rental so two requests cannot both issue its invoice.
+	if err := tx.Model(rental).UpdateColumn("updated_at", gorm.Expr("updated_at")).Error; err != nil {
+		return nil, false, err
+	}
+	if existing, err := findInvoice(tx, "rental_id = ?", rental.ID); existing != nil || err != nil {
+		return existing, false, err
+	}
+
+	rentalID := rental.ID
+	invoice := models.Invoice{UserID: rental.UserID, RentalID: &rentalID}
+	if err := addInvoiceRentals(tx, &invoice, []models.RentalHistory{*rental}); err != nil {
+		return nil, false, err
+	}
+	issued, err := issueInvoice(tx, &invoice)
+	if err != nil {
+		return nil, false, err
+	}
+	return issued, true, nil
+}
+
+// invoiceOrder issues the invoice for a returned order, covering every rental
+// of it that was not cancelled, or returns the one already issued that is not
+// void. created reports whether the invoice was issued by this call.
+func invoiceOrder(tx *gorm.DB, orderID uint) (*models.Invoice, bool, error) {
+	var order models.RentalOrder
+	if err := tx.First(&order, orderID).Error; err != nil {
+		return nil, false, err
+	}
+	if err := tx.Model(&order).UpdateColumn("updated_at", gorm.Expr("updated_at")).Error; err != nil {
+		return nil, false, err
+	}
+	if order.Status != models.OrderReturned {
+		return nil, false, models.ErrInvoiceNotReady
+	}
+
+	if existing, err := findInvoice(tx, "order_id = ?", order.ID); existing != nil || err != nil {
+		return existing, false, err
+	}
+
+	var rentals []models.RentalHistory
+	if err := tx.Where("order_id = ? AND status = ?", order.ID, models.RentalReturned).
+		Order("id").Find(&rentals).Error; err != nil {
+		return nil, false, err
+	}
+
+	invoice := models.Invoice{UserID: order.UserID, OrderID: &order.ID}
+	if err := addInvoiceRentals(tx, &invoice, rentals); err != nil {
+		return nil, false, err
+	}
+	issued, err := issueInvoice(tx, &invoice)
+	if err != nil {
+		return nil, false, err
+	}
+	return issued, true, nil
+}
+
+// findInvoice returns the invoice matching query that is not void, or nil.
+func findInvoice(tx *gorm.DB, query string, args ...interface{}) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := preloadInvoice(tx).Where(query, args...).Where("status <> ?", models.InvoiceVoid).First(&invoice).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+func addInvoiceRentals(tx *gorm.DB, invoice *models.Invoice, rentals []models.RentalHistory) error {
+	machineIDs := make([]uint, 0, len(rentals))
+	for _, rental := range rentals {
+		machineIDs = append(machineIDs, rental.MachineID)
+	}
+
+	var machines []models.MesinBor
+	if err := tx.Unscoped().Where("id IN ?", machineIDs).Find(&machines).Error; err != nil {
+		return err
+	}
+	names := make(map[uint]string, len(machines))
+	for _, machine := range machines {
+		names[machine.ID] = machine.Name
+	}
+
+	rentalIDs := make([]uint, 0, len(rentals))
+	for i := range rentals {
+		invoice.AddRental(&rentals[i], names[rentals[i].MachineID])
+		rentalIDs = append(rentalIDs, rentals[i].ID)
+	}
+
+	// The late fees and damage were forfeited from the deposits on return;
+	// credit that here so the customer is not billed for them again.
+	return tx.Model(&models.DepositEntry{}).
+		Where("rental_id IN ? AND kind = ?", rentalIDs, models.DepositForfeit).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&invoice.DepositApplied).Error
+}
+
+// issueInvoice numbers, taxes and saves the invoice. The sequence row stays
+// locked until the transaction ends, so numbers are handed out in order and a
+// rolled back invoice does not leave a gap.
+func issueInvoice(tx *gorm.DB, invoice *models.Invoice) (*models.Invoice, error) {
+	result := tx.Table("invoice_sequences").
+		Where("name = ?", invoiceSequence).
+		UpdateColumn("next_value", gorm.Expr("next_value + 1"))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("invoice sequence is missing")
+	}
+
+	var next int
+	if err := tx.Table("invoice_sequences").
+		Where("name = ?", invoiceSequence).
+		Select("next_value").
+		Scan(&next).Error; err != nil {
+		return nil, err
+	}
+
+	invoice.Number = models.InvoiceNumber(next - 1)
+	invoice.Status = models.InvoiceIssued
+	invoice.IssueDate = time.Now()
+	invoice.ApplyTaxes(models.DefaultTaxRates())
+
+	if err := tx.Create(invoice).Error; err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
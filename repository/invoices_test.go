@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"math"
+	"rental-api/models"
+	"testing"
+	"time"
+)
+
+func TestInvoiceCreditsForfeitedDepositAndCanBeReissued(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+
+	machine := models.MesinBor{Name: "Bosch GSB 13 RE", StockAvailability: 1, RentalCosts: 100000, DepositAmount: 150000}
+	if err := repos.Machines.Create(ctx, &machine); err != nil {
+		t.Fatalf("failed to create machine: %v", err)
+	}
+
+	now := time.Now()
+	due := now.AddDate(0, 0, -3)
+	rental := models.RentalHistory{UserID: 1, MachineID: machine.ID, RentalDate: now.AddDate(0, 0, -5), DueDate: &due}
+	if err := repos.Rentals.Create(ctx, &rental); err != nil {
+		t.Fatalf("failed to create rental: %v", err)
+	}
+	for _, status := range []string{models.RentalConfirmed, models.RentalPickedUp} {
+		if _, err := repos.Rentals.Transition(ctx, int(rental.ID), status, nil, ""); err != nil {
+			t.Fatalf("failed to move rental to %s: %v", status, err)
+		}
+	}
+	returned, err := repos.Rentals.MarkAsReturned(ctx, int(rental.ID), now, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to return rental: %v", err)
+	}
+	if returned.LateFee <= 0 {
+		t.Fatalf("expected a late fee, got %v", returned.LateFee)
+	}
+
+	invoice, created, err := repos.Invoices.GenerateForRental(ctx, rental.ID)
+	if err != nil {
+		t.Fatalf("failed to get invoice: %v", err)
+	}
+	if created {
+		t.Errorf("expected the invoice issued on return, got a new one")
+	}
+	forfeited := math.Min(returned.LateFee, machine.DepositAmount)
+	if invoice.DepositApplied != forfeited {
+		t.Errorf("expected %v of deposit applied, got %v", forfeited, invoice.DepositApplied)
+	}
+	if want := math.Round((invoice.Subtotal+invoice.TaxTotal-forfeited)*100) / 100; invoice.Total != want {
+		t.Errorf("expected total %v, got %v", want, invoice.Total)
+	}
+
+	if _, err := repos.Invoices.UpdateStatus(ctx, int(invoice.ID), models.InvoiceVoid); err != nil {
+		t.Fatalf("failed to void invoice: %v", err)
+	}
+	reissued, created, err := repos.Invoices.GenerateForRental(ctx, rental.ID)
+	if err != nil {
+		t.Fatalf("failed to reissue invoice: %v", err)
+	}
+	if !created {
+		t.Errorf("expected the reissued invoice to be reported as created")
+	}
+	if reissued.ID == invoice.ID || reissued.Number == invoice.Number {
+		t.Errorf("expected a new invoice, got %s again", reissued.Number)
+	}
+	if reissued.Status != models.InvoiceIssued || reissued.Total != invoice.Total {
+		t.Errorf("expected an issued invoice for %v, got %s for %v", invoice.Total, reissued.Status, reissued.Total)
+	}
+
+	again, created, err := repos.Invoices.GenerateForRental(ctx, rental.ID)
+	if err != nil {
+		t.Fatalf("failed to get invoice: %v", err)
+	}
+	if created {
+		t.Errorf("expected the reissued invoice to be returned, got a new one")
+	}
+	if again.ID != reissued.ID {
+		t.Errorf("expected the reissued invoice %s, got %s", reissued.Number, again.Number)
+	}
+}
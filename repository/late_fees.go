@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"rental-api/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type gormLateFeePolicyRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormLateFeePolicyRepository) List(ctx context.Context) ([]models.LateFeePolicy, error) {
+	var policies []models.LateFeePolicy
+	if err := conn(ctx, r.db).Order("id").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// Save creates the policy, or replaces the existing policy for the same
+// machine or category.
+func (r *gormLateFeePolicyRepository) Save(ctx context.Context, policy *models.LateFeePolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	return transaction(ctx, r.db, func(tx *gorm.DB) error {
+		if policy.MachineID != nil {
+			var count int64
+			if err := tx.Model(&models.MesinBor{}).Where("id = ?", *policy.MachineID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				return models.ErrMachineNotFound
+			}
+		}
+
+		var existing models.LateFeePolicy
+		var query *gorm.DB
+		if policy.MachineID != nil {
+			query = tx.Where("machine_id = ?", *policy.MachineID)
+		} else {
+			query = tx.Where("category = ?", *policy.Category)
+		}
+		err := query.First(&existing).Error
+		switch {
+		case err == nil:
+			policy.ID = existing.ID
+			policy.CreatedAt = existing.CreatedAt
+			return tx.Save(policy).Error
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return tx.Create(policy).Error
+		default:
+			return err
+		}
+	})
+}
+
+func (r *gormLateFeePolicyRepository) Delete(ctx context.Context, id int) error {
+	result := conn(ctx, r.db).Delete(&models.LateFeePolicy{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// resolveLateFeePolicy picks the machine's own policy, then its category's,
+// then the default.
+func resolveLateFeePolicy(tx *gorm.DB, machine *models.MesinBor) (models.LateFeePolicy, error) {
+	var policy models.LateFeePolicy
+	err := tx.Where("machine_id = ?", machine.ID).First(&policy).Error
+	if err == nil {
+		return policy, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return policy, err
+	}
+
+	err = tx.Where("category = ?", machine.Category).First(&policy).Error
+	if err == nil {
+		return policy, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return policy, err
+	}
+	return models.DefaultLateFeePolicy(), nil
+}
+
+// applyLateFee sets the rental's late fee for the time it was kept past its
+// due date up to "at".
+func applyLateFee(tx *gorm.DB, rental *models.RentalHistory, machine *models.MesinBor, at time.Time) error {
+	if rental.DueDate == nil {
+		return nil
+	}
+
+	policy, err := resolveLateFeePolicy(tx, machine)
+	if err != nil {
+		return err
+	}
+	rental.OverdueDays, rental.LateFee = policy.LateFee(machine.RentalCosts, *rental.DueDate, at)
+	return nil
+}
+
+// FlagOverdue moves every picked-up or active rental whose due date has passed
+// to the overdue status. Each rental is handled in its own transaction so one
+// failure does not hold back the rest.
+func (r *gormRentalRepository) FlagOverdue(ctx context.Context, now time.Time) (int, error) {
+	var rentals []models.RentalHistory
+	if err := conn(ctx, r.db).
+		Where("status IN ? AND due_date IS NOT NULL AND due_date < ?",
+			[]string{models.RentalPickedUp, models.RentalActive}, now).
+		Find(&rentals).Error; err != nil {
+		return 0, err
+	}
+
+	flagged := 0
+	for i := range rentals {
+		rental := rentals[i]
+		err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+			return transitionRental(tx, &rental, models.RentalOverdue, nil, "Due date passed")
+		})
+		if err != nil {
+			var invalid *models.InvalidTransitionError
+			if !errors.As(err, &invalid) {
+				log.Printf("Failed to flag rental %d as overdue: %v", rental.ID, err)
+			}
+			continue
+		}
+		flagged++
+	}
+	return flagged, nil
+}
+
+// AccrueLateFees recomputes the late fee of every overdue rental as of now.
+func (r *gormRentalRepository) AccrueLateFees(ctx context.Context, now time.Time) (int, error) {
+	var rentals []models.RentalHistory
+	if err := conn(ctx, r.db).Where("status = ?", models.RentalOverdue).Find(&rentals).Error; err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for i := range rentals {
+		rental := rentals[i]
+		err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+			var machine models.MesinBor
+			if err := tx.Unscoped().First(&machine, rental.MachineID).Error; err != nil {
+				return err
+			}
+			if err := applyLateFee(tx, &rental, &machine, now); err != nil {
+				return err
+			}
+			return tx.Model(&rental).
+				Where("status = ?", models.RentalOverdue).
+				Select("late_fee", "overdue_days").
+				Updates(&rental).Error
+		})
+		if err != nil {
+			log.Printf("Failed to accrue late fee for rental %d: %v", rental.ID, err)
+			continue
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+func (r *gormRentalRepository) ListOverdue(ctx context.Context) ([]models.RentalHistory, error) {
+	var rentals []models.RentalHistory
+	if err := conn(ctx, r.db).Where("status = ?", models.RentalOverdue).Order("due_date").Find(&rentals).Error; err != nil {
+		return nil, err
+	}
+	return rentals, nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"rental-api/models"
+	"testing"
+	"time"
+)
+
+func TestReturnChargesLateFeeByPolicy(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+
+	createMachine := func(name, category string) *models.MesinBor {
+		machine := models.MesinBor{Name: name, Category: category, StockAvailability: 10, RentalCosts: 50000}
+		if err := repos.Machines.Create(ctx, &machine); err != nil {
+			t.Fatalf("failed to create machine: %v", err)
+		}
+		return &machine
+	}
+	ownPolicy := createMachine("Hilti TE 70", "Hammer")
+	categoryPolicy := createMachine("Bosch GBH 8-45", "Hammer")
+	noPolicy := createMachine("Makita DF333", "Driver")
+
+	category := "Hammer"
+	policies := []models.LateFeePolicy{
+		{MachineID: &ownPolicy.ID, DailyFee: 10000, GraceHours: 2, MaxFee: 25000},
+		{Category: &category, RateMultiplier: 0.5},
+	}
+	for i := range policies {
+		if err := repos.LateFees.Save(ctx, &policies[i]); err != nil {
+			t.Fatalf("failed to save policy: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		machine *models.MesinBor
+		lateBy  time.Duration
+		days    int
+		fee     float64
+	}{
+		{name: "on time", machine: ownPolicy, lateBy: 0, days: 0, fee: 0},
+		{name: "within grace period", machine: ownPolicy, lateBy: 2 * time.Hour, days: 0, fee: 0},
+		{name: "just past grace period", machine: ownPolicy, lateBy: 2*time.Hour + time.Minute, days: 1, fee: 10000},
+		{name: "grace period is not charged", machine: ownPolicy, lateBy: 50 * time.Hour, days: 2, fee: 20000},
+		{name: "capped", machine: ownPolicy, lateBy: 5 * 24 * time.Hour, days: 5, fee: 25000},
+		{name: "category policy", machine: categoryPolicy, lateBy: time.Minute, days: 1, fee: 25000},
+		{name: "category policy without cap", machine: categoryPolicy, lateBy: 72 * time.Hour, days: 3, fee: 75000},
+		{name: "default policy", machine: noPolicy, lateBy: 25 * time.Hour, days: 2, fee: 100000},
+	}
+
+	due := time.Now().Add(-10 * 24 * time.Hour).Truncate(time.Second)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rental := models.RentalHistory{UserID: 1, MachineID: tt.machine.ID, RentalDate: due.Add(-48 * time.Hour), DueDate: &due}
+			if err := repos.Rentals.Create(ctx, &rental); err != nil {
+				t.Fatalf("failed to create rental: %v", err)
+			}
+			for _, status := range []string{models.RentalConfirmed, models.RentalPickedUp} {
+				if _, err := repos.Rentals.Transition(ctx, int(rental.ID), status, nil, ""); err != nil {
+					t.Fatalf("failed to move rental to %s: %v", status, err)
+				}
+			}
+
+			returned, err := repos.Rentals.MarkAsReturned(ctx, int(rental.ID), due.Add(tt.lateBy), nil, nil)
+			if err != nil {
+				t.Fatalf("failed to return rental: %v", err)
+			}
+			if returned.OverdueDays != tt.days || returned.LateFee != tt.fee {
+				t.Errorf("expected %d overdue days and a fee of %v, got %d and %v", tt.days, tt.fee, returned.OverdueDays, returned.LateFee)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"rental-api/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type gormMachineRepository struct {
+	db *gorm.DB
+}
+
+// machineSorts maps the catalog's sort options to ORDER BY clauses.
+var machineSorts = map[string]string{
+	"":        "id",
+	"name":    "name, id",
+	"score":   "rating_score DESC, rating_count DESC, id",
+	"rating":  "rating_average DESC, rating_count DESC, id",
+	"reviews": "rating_count DESC, id",
+}
+
+func (r *gormMachineRepository) List(ctx context.Context, sort string) ([]models.MesinBor, error) {
+	order, ok := machineSorts[sort]
+	if !ok {
+		return nil, ErrInvalidSort
+	}
+
+	var machines []models.MesinBor
+	if err := conn(ctx, r.db).Order(order).Find(&machines).Error; err != nil {
+		return nil, err
+	}
+	return machines, nil
+}
+
+func (r *gormMachineRepository) GetByID(ctx context.Context, id int) (*models.MesinBor, error) {
+	var machine models.MesinBor
+	if err := conn(ctx, r.db).First(&machine, id).Error; err != nil {
+		return nil, err
+	}
+	return &machine, nil
+}
+
+func (r *gormMachineRepository) Create(ctx context.Context, machine *models.MesinBor) error {
+	machine.Ratings = models.RatingSummary{Score: models.BayesianScore(0, 0)}
+	return conn(ctx, r.db).Create(machine).Error
+}
+
+func (r *gormMachineRepository) Update(ctx context.Context, id int, updatedMachine *models.MesinBor) error {
+	db := conn(ctx, r.db)
+
+	var machine models.MesinBor
+	if err := db.First(&machine, id).Error; err != nil {
+		return err
+	}
+
+	return db.Model(&machine).Updates(updatedMachine).Error
+}
+
+func (r *gormMachineRepository) Delete(ctx context.Context, id int) error {
+	return conn(ctx, r.db).Delete(&models.MesinBor{}, id).Error
+}
+
+// Availability returns the daily free units of the machine from "from" to "to"
+// inclusive, based on its capacity, open rentals, reservations and open
+// maintenance records.
+func (r *gormMachineRepository) Availability(ctx context.Context, id int, from, to time.Time) (*models.Availability, error) {
+	var availability *models.Availability
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		var machine models.MesinBor
+		if err := tx.First(&machine, id).Error; err != nil {
+			return err
+		}
+
+		capacity, err := machineCapacity(tx, &machine)
+		if err != nil {
+			return err
+		}
+
+		end := to.AddDate(0, 0, 1)
+		bookings, err := overlappingBookings(tx, machine.ID, from, end)
+		if err != nil {
+			return err
+		}
+
+		maintenance, err := activeMaintenance(tx, machine.ID, from, end)
+		if err != nil {
+			return err
+		}
+
+		availability = models.ComputeAvailability(machine.ID, capacity, bookings, maintenance, from, to)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return availability, nil
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"rental-api/models"
+
+	"gorm.io/gorm"
+)
+
+type gormMaintenanceRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormMaintenanceRepository) Create(ctx context.Context, maintenance *models.Maintenance) error {
+	return conn(ctx, r.db).Create(maintenance).Error
+}
+
+func (r *gormMaintenanceRepository) GetByID(ctx context.Context, id int) (*models.Maintenance, error) {
+	var maintenance models.Maintenance
+	if err := conn(ctx, r.db).First(&maintenance, id).Error; err != nil {
+		return nil, err
+	}
+	return &maintenance, nil
+}
+
+var maintenanceSorts = sortFields{
+	fallback: "-created_at",
+	columns: map[string]string{
+		"id":         "id",
+		"created_at": "created_at",
+		"fixed_at":   "fixed_at",
+	},
+}
+
+// List returns a page of the maintenance records matching filter.
+func (r *gormMaintenanceRepository) List(ctx context.Context, filter MaintenanceFilter, page Page) ([]models.Maintenance, *PageInfo, error) {
+	query := conn(ctx, r.db).Model(&models.Maintenance{})
+	if filter.MachineID != nil {
+		query = query.Where("machine_id = ?", *filter.MachineID)
+	}
+	if filter.Fixed != nil {
+		query = query.Where("fixed = ?", *filter.Fixed)
+	}
+	query = filter.Created.apply(query, "created_at")
+
+	var records []models.Maintenance
+	info, err := paginate(query, page, maintenanceSorts, &records)
+	if err != nil {
+		return nil, nil, err
+	}
+	return records, info, nil
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"rental-api/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type gormNotificationRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormNotificationRepository) ListByUser(ctx context.Context, userID uint, unreadOnly bool) ([]models.Notification, error) {
+	query := conn(ctx, r.db).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var notifications []models.Notification
+	if err := query.Order("created_at DESC, id DESC").Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// MarkRead marks one of the user's notifications as read. Notifications of
+// other users are reported as not found.
+func (r *gormNotificationRepository) MarkRead(ctx context.Context, id int, userID uint) (*models.Notification, error) {
+	db := conn(ctx, r.db)
+
+	var notification models.Notification
+	if err := db.Where("user_id = ?", userID).First(&notification, id).Error; err != nil {
+		return nil, err
+	}
+	if notification.ReadAt != nil {
+		return &notification, nil
+	}
+
+	now := time.Now()
+	if err := db.Model(&notification).Update("read_at", now).Error; err != nil {
+		return nil, err
+	}
+	notification.ReadAt = &now
+	return &notification, nil
+}
+
+// notify stores a notification as part of the change that caused it.
+func notify(tx *gorm.DB, notification *models.Notification) error {
+	return tx.Create(notification).Error
+}
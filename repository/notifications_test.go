@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"rental-api/models"
+	"testing"
+)
+
+func TestReplyNotifiesReviewer(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+	machine := createTestMachine(t, repos, "Bosch GSR 12V", 2)
+
+	submit := func(userID uint, blocked ...string) *models.Review {
+		rental := returnTestRental(t, repos, machine.ID, userID)
+		review := models.Review{UserID: userID, RentalID: &rental.ID, Rating: 4}
+		review.Screen(blocked)
+		if err := repos.Reviews.Create(ctx, &review); err != nil {
+			t.Fatalf("failed to create review: %v", err)
+		}
+		return &review
+	}
+	published := submit(1)
+	held := submit(2, "scam")
+
+	if _, err := repos.Reviews.SaveReply(ctx, int(published.ID), 9, "   "); !errors.Is(err, models.ErrEmptyReply) {
+		t.Errorf("expected ErrEmptyReply for a blank reply, got %v", err)
+	}
+	if _, err := repos.Reviews.SaveReply(ctx, int(held.ID), 9, "Thanks"); !errors.Is(err, models.ErrReviewNotPublished) {
+		t.Errorf("expected ErrReviewNotPublished for a review in moderation, got %v", err)
+	}
+	for _, userID := range []uint{1, 2} {
+		if notifications, err := repos.Notifications.ListByUser(ctx, userID, false); err != nil || len(notifications) != 0 {
+			t.Fatalf("expected no notifications for user %d after failed replies, got %d (%v)", userID, len(notifications), err)
+		}
+	}
+
+	reply, err := repos.Reviews.SaveReply(ctx, int(published.ID), 9, " Thanks for renting! ")
+	if err != nil {
+		t.Fatalf("failed to reply: %v", err)
+	}
+	if reply.Body != "Thanks for renting!" || reply.AuthorID != 9 {
+		t.Errorf("unexpected reply %+v", reply)
+	}
+
+	edited, err := repos.Reviews.SaveReply(ctx, int(published.ID), 10, "Thanks again!")
+	if err != nil {
+		t.Fatalf("failed to edit reply: %v", err)
+	}
+	if edited.ID != reply.ID || edited.AuthorID != 10 || edited.Body != "Thanks again!" {
+		t.Errorf("expected the reply to be edited in place, got %+v", edited)
+	}
+
+	notifications, err := repos.Notifications.ListByUser(ctx, 1, false)
+	if err != nil {
+		t.Fatalf("failed to list notifications: %v", err)
+	}
+	if len(notifications) != 2 {
+		t.Fatalf("expected 2 notifications, got %d", len(notifications))
+	}
+	// Newest first.
+	if notifications[0].Kind != models.NotificationReviewReplyEdited || notifications[1].Kind != models.NotificationReviewReply {
+		t.Errorf("expected an edit after a reply, got %s and %s", notifications[0].Kind, notifications[1].Kind)
+	}
+	for _, notification := range notifications {
+		if notification.ResourceType != "review" || notification.ResourceID == nil || *notification.ResourceID != published.ID {
+			t.Errorf("expected the notification to point at review %d, got %s %v", published.ID, notification.ResourceType, notification.ResourceID)
+		}
+		if notification.ReadAt != nil {
+			t.Errorf("expected new notifications to be unread")
+		}
+	}
+	if others, err := repos.Notifications.ListByUser(ctx, 2, false); err != nil || len(others) != 0 {
+		t.Errorf("expected no notifications for another reviewer, got %d (%v)", len(others), err)
+	}
+}
+
+func TestMarkNotificationRead(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+	machine := createTestMachine(t, repos, "Bosch GSR 18V-55", 1)
+
+	rental := returnTestRental(t, repos, machine.ID, 1)
+	review := models.Review{UserID: 1, RentalID: &rental.ID, Rating: 5}
+	review.Screen(nil)
+	if err := repos.Reviews.Create(ctx, &review); err != nil {
+		t.Fatalf("failed to create review: %v", err)
+	}
+	for _, body := range []string{"Thanks!", "Thanks a lot!"} {
+		if _, err := repos.Reviews.SaveReply(ctx, int(review.ID), 9, body); err != nil {
+			t.Fatalf("failed to reply: %v", err)
+		}
+	}
+
+	unread, err := repos.Notifications.ListByUser(ctx, 1, true)
+	if err != nil {
+		t.Fatalf("failed to list notifications: %v", err)
+	}
+	if len(unread) != 2 {
+		t.Fatalf("expected 2 unread notifications, got %d", len(unread))
+	}
+
+	if _, err := repos.Notifications.MarkRead(ctx, int(unread[0].ID), 2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for someone else's notification, got %v", err)
+	}
+
+	read, err := repos.Notifications.MarkRead(ctx, int(unread[0].ID), 1)
+	if err != nil {
+		t.Fatalf("failed to mark notification read: %v", err)
+	}
+	if read.ReadAt == nil {
+		t.Fatalf("expected the notification to be read")
+	}
+
+	again, err := repos.Notifications.MarkRead(ctx, int(unread[0].ID), 1)
+	if err != nil {
+		t.Fatalf("failed to mark notification read again: %v", err)
+	}
+	if again.ReadAt == nil || !again.ReadAt.Equal(*read.ReadAt) {
+		t.Errorf("expected marking read twice to keep the first time, got %v and %v", read.ReadAt, again.ReadAt)
+	}
+
+	unread, err = repos.Notifications.ListByUser(ctx, 1, true)
+	if err != nil {
+		t.Fatalf("failed to list notifications: %v", err)
+	}
+	if len(unread) != 1 || unread[0].ID == read.ID {
+		t.Errorf("expected only the other notification to be unread, got %d", len(unread))
+	}
+	all, err := repos.Notifications.ListByUser(ctx, 1, false)
+	if err != nil {
+		t.Fatalf("failed to list notifications: %v", err)
+	}
+	if len(all) != 2 {
+		t.Errorf("expected read notifications to stay listed, got %d", len(all))
+	}
+}
@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"math"
+	"rental-api/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type gormOrderRepository struct {
+	db *gorm.DB
+}
+
+// Create inserts the order and one rental per unit of each line in a single
+// transaction, so either every unit is reserved or none is. A line that
+// cannot be reserved fails with a *models.OrderLineError.
+func (r *gormOrderRepository) Create(ctx context.Context, order *models.RentalOrder) error {
+	if len(order.Lines) == 0 {
+		return models.ErrEmptyOrder
+	}
+	for _, line := range order.Lines {
+		if line.Quantity < 1 {
+			return models.ErrInvalidQuantity
+		}
+	}
+
+	lines := order.Lines
+	order.Lines = nil
+	order.Rentals = nil
+	order.Status = models.OrderOpen
+
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		if err := tx.Create(order).Error; err != nil {
+			return err
+		}
+
+		for i := range lines {
+			line := &lines[i]
+			line.ID = 0
+			line.OrderID = order.ID
+			if err := tx.Create(line).Error; err != nil {
+				return err
+			}
+
+			for n := 0; n < line.Quantity; n++ {
+				rental := models.RentalHistory{
+					UserID:     order.UserID,
+					MachineID:  line.MachineID,
+					RentalDate: order.RentalDate,
+					DueDate:    order.DueDate,
+				}
+				if err := createRental(tx, &rental, line); err != nil {
+					return &models.OrderLineError{MachineID: line.MachineID, Err: err}
+				}
+			}
+		}
+
+		return refreshOrder(tx, order.ID)
+	})
+	if err != nil {
+		order.ID = 0
+		order.Lines = lines
+		return err
+	}
+
+	return loadOrder(conn(ctx, r.db), order, order.ID)
+}
+
+func (r *gormOrderRepository) GetByID(ctx context.Context, id int) (*models.RentalOrder, error) {
+	var order models.RentalOrder
+	if err := loadOrder(conn(ctx, r.db), &order, uint(id)); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *gormOrderRepository) List(ctx context.Context) ([]models.RentalOrder, error) {
+	var orders []models.RentalOrder
+	if err := conn(ctx, r.db).Preload("Lines").Order("id").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (r *gormOrderRepository) ListByUser(ctx context.Context, userID uint) ([]models.RentalOrder, error) {
+	var orders []models.RentalOrder
+	if err := conn(ctx, r.db).Preload("Lines").Where("user_id = ?", userID).Order("id").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// Transition moves every open rental of the order to the given status. If any
+// of them cannot make the move, none does.
+func (r *gormOrderRepository) Transition(ctx context.Context, id int, to string, actorID *uint, note string) (*models.RentalOrder, error) {
+	var order models.RentalOrder
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		rentals, err := openOrderRentals(tx, uint(id), nil)
+		if err != nil {
+			return err
+		}
+		for i := range rentals {
+			if err := transitionRental(tx, &rentals[i], to, actorID, note); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := loadOrder(conn(ctx, r.db), &order, uint(id)); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// Return returns every open rental of the order at once.
+func (r *gormOrderRepository) Return(ctx context.Context, id int, returnDate time.Time, actorID *uint) (*models.RentalOrder, error) {
+	return r.returnRentals(ctx, id, nil, 0, returnDate, actorID)
+}
+
+// ReturnLine returns quantity units of one line, or all of its open units
+// when quantity is 0. The rest of the order stays out.
+func (r *gormOrderRepository) ReturnLine(ctx context.Context, id int, lineID uint, quantity int, returnDate time.Time, actorID *uint) (*models.RentalOrder, error) {
+	if quantity < 0 {
+		return nil, models.ErrInvalidQuantity
+	}
+	return r.returnRentals(ctx, id, &lineID, quantity, returnDate, actorID)
+}
+
+func (r *gormOrderRepository) returnRentals(ctx context.Context, id int, lineID *uint, quantity int, returnDate time.Time, actorID *uint) (*models.RentalOrder, error) {
+	var order models.RentalOrder
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		if lineID != nil {
+			var count int64
+			if err := tx.Model(&models.RentalOrderLine{}).
+				Where("id = ? AND order_id = ?", *lineID, id).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				return models.ErrOrderLineNotFound
+			}
+		}
+
+		rentals, err := openOrderRentals(tx, uint(id), lineID)
+		if err != nil {
+			return err
+		}
+		if quantity > 0 {
+			if quantity > len(rentals) {
+				return models.ErrReturnQuantityTooBig
+			}
+			rentals = rentals[:quantity]
+		}
+
+		for i := range rentals {
+			if err := returnRental(tx, &rentals[i], returnDate, actorID, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := loadOrder(conn(ctx, r.db), &order, uint(id)); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func loadOrder(db *gorm.DB, order *models.RentalOrder, id uint) error {
+	return db.
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Rentals", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		First(order, id).Error
+}
+
+// openOrderRentals returns the order's rentals that are not yet returned or
+// cancelled, optionally only those of one line. It fails with
+// models.ErrOrderClosed when there are none.
+func openOrderRentals(tx *gorm.DB, orderID uint, lineID *uint) ([]models.RentalHistory, error) {
+	var order models.RentalOrder
+	if err := tx.First(&order, orderID).Error; err != nil {
+		return nil, err
+	}
+
+	query := tx.Scopes(openRentals).Where("order_id = ?", orderID)
+	if lineID != nil {
+		query = query.Where("order_line_id = ?", *lineID)
+	}
+
+	var rentals []models.RentalHistory
+	if err := query.Order("id").Find(&rentals).Error; err != nil {
+		return nil, err
+	}
+	if len(rentals) == 0 {
+		return nil, models.ErrOrderClosed
+	}
+	return rentals, nil
+}
+
+// refreshOrder recomputes the order's total and status from its rentals and
+// issues the invoice once the whole order is back.
+func refreshOrder(tx *gorm.DB, orderID uint) error {
+	var rentals []models.RentalHistory
+	if err := tx.Where("order_id = ?", orderID).Find(&rentals).Error; err != nil {
+		return err
+	}
+
+	var total float64
+	for _, rental := range rentals {
+		if rental.Status != models.RentalCancelled {
+			total += rental.TotalCost
+		}
+	}
+
+	status := models.OrderStatusFor(rentals)
+	if err := tx.Model(&models.RentalOrder{}).Where("id = ?", orderID).Updates(map[string]interface{}{
+		"total_cost": math.Round(total*100) / 100,
+		"status":     status,
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		return err
+	}
+
+	if status == models.OrderReturned {
+		if _, _, err := invoiceOrder(tx, orderID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
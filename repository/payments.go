@@ -0,0 +1,278 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math"
+	"rental-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormPaymentRepository struct {
+	db *gorm.DB
+}
+
+// Create records a pending payment or refund against an invoice. Pending
+// payments count towards the invoice total, so two concurrent payments cannot
+// both settle the same balance.
+func (r *gormPaymentRepository) Create(ctx context.Context, payment *models.Payment) error {
+	if payment.Amount <= 0 {
+		return models.ErrInvalidPaymentAmount
+	}
+
+	return transaction(ctx, r.db, func(tx *gorm.DB) error {
+		invoice, err := lockInvoice(tx, payment.InvoiceID)
+		if err != nil {
+			return err
+		}
+		if invoice.Status == models.InvoiceVoid {
+			return models.ErrInvoiceVoid
+		}
+
+		payment.UserID = invoice.UserID
+		payment.Status = models.PaymentPending
+
+		if payment.Kind == models.PaymentKindRefund {
+			if err := checkRefund(tx, payment); err != nil {
+				return err
+			}
+		} else {
+			payment.Kind = models.PaymentKindPayment
+			payment.RefundOfID = nil
+
+			committed, err := paymentTotal(tx.Where("invoice_id = ? AND kind = ? AND status IN ?",
+				invoice.ID, models.PaymentKindPayment, []string{models.PaymentPending, models.PaymentSucceeded}))
+			if err != nil {
+				return err
+			}
+			refunded, err := paymentTotal(tx.Where("invoice_id = ? AND kind = ? AND status = ?",
+				invoice.ID, models.PaymentKindRefund, models.PaymentSucceeded))
+			if err != nil {
+				return err
+			}
+			if payment.Amount > math.Round((invoice.Total-committed+refunded)*100)/100 {
+				return models.ErrOverpayment
+			}
+		}
+
+		return tx.Create(payment).Error
+	})
+}
+
+// checkRefund makes sure the refund points at a succeeded payment of the same
+// invoice and, together with its other refunds, does not exceed it.
+func checkRefund(tx *gorm.DB, refund *models.Payment) error {
+	if refund.RefundOfID == nil {
+		return models.ErrRefundTarget
+	}
+
+	var original models.Payment
+	err := tx.First(&original, *refund.RefundOfID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.ErrRefundTarget
+	}
+	if err != nil {
+		return err
+	}
+	if original.InvoiceID != refund.InvoiceID || original.Kind != models.PaymentKindPayment || original.Status != models.PaymentSucceeded {
+		return models.ErrRefundTarget
+	}
+
+	refunded, err := paymentTotal(tx.Where("refund_of_id = ? AND status IN ?",
+		original.ID, []string{models.PaymentPending, models.PaymentSucceeded}))
+	if err != nil {
+		return err
+	}
+	if refund.Amount > math.Round((original.Amount-refunded)*100)/100 {
+		return models.ErrRefundTooLarge
+	}
+
+	refund.Method = original.Method
+	return nil
+}
+
+// SetStatus settles a pending payment with what the gateway reported. A
+// pending status only records the gateway's reference.
+func (r *gormPaymentRepository) SetStatus(ctx context.Context, id uint, status string, externalID string) (*models.Payment, error) {
+	var payment models.Payment
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		if err := tx.First(&payment, id).Error; err != nil {
+			return err
+		}
+		return settlePayment(tx, &payment, status, externalID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *gormPaymentRepository) GetByID(ctx context.Context, id int) (*models.Payment, error) {
+	var payment models.Payment
+	if err := conn(ctx, r.db).First(&payment, id).Error; err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *gormPaymentRepository) ListByInvoice(ctx context.Context, invoiceID uint) ([]models.Payment, error) {
+	var payments []models.Payment
+	if err := conn(ctx, r.db).Where("invoice_id = ?", invoiceID).Order("created_at, id").Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// Balance sums the user's invoices that are not void against their settled
+// payments and refunds.
+func (r *gormPaymentRepository) Balance(ctx context.Context, userID uint) (*models.AccountBalance, error) {
+	db := conn(ctx, r.db)
+	balance := models.AccountBalance{UserID: userID}
+
+	if err := db.Model(&models.Invoice{}).
+		Where("user_id = ? AND status <> ?", userID, models.InvoiceVoid).
+		Select("COALESCE(SUM(total), 0)").
+		Scan(&balance.Invoiced).Error; err != nil {
+		return nil, err
+	}
+
+	var err error
+	balance.Paid, err = paymentTotal(db.Where("user_id = ? AND kind = ? AND status = ?",
+		userID, models.PaymentKindPayment, models.PaymentSucceeded))
+	if err != nil {
+		return nil, err
+	}
+	balance.Refunded, err = paymentTotal(db.Where("user_id = ? AND kind = ? AND status = ?",
+		userID, models.PaymentKindRefund, models.PaymentSucceeded))
+	if err != nil {
+		return nil, err
+	}
+
+	balance.Outstanding = math.Round((balance.Invoiced-balance.Paid+balance.Refunded)*100) / 100
+	return &balance, nil
+}
+
+// ProcessWebhook applies a verified gateway event to the payment it refers
+// to. The event is recorded first and its ID is unique per gateway, so a
+// redelivered event, even one arriving at the same time as the first, is
+// reported as a duplicate and changes nothing. Events for a payment that is
+// already settled are recorded and otherwise ignored: the gateway may repeat
+// a final status under a new event ID, and it only needs to hear that the
+// event arrived.
+func (r *gormPaymentRepository) ProcessWebhook(ctx context.Context, event *models.WebhookEvent) (bool, error) {
+	duplicate := false
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(event)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			duplicate = true
+			return nil
+		}
+
+		var payment models.Payment
+		if err := tx.Where("method = ? AND external_id = ?", event.Gateway, event.ExternalID).
+			First(&payment).Error; err != nil {
+			return err
+		}
+		if payment.Status != models.PaymentPending {
+			return nil
+		}
+		err := settlePayment(tx, &payment, event.Status, "")
+		if errors.Is(err, models.ErrPaymentNotPending) {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return duplicate, nil
+}
+
+// settlePayment moves a pending payment to status and updates the invoice it
+// belongs to.
+func settlePayment(tx *gorm.DB, payment *models.Payment, status string, externalID string) error {
+	updates := map[string]interface{}{"status": status}
+	if externalID != "" {
+		updates["external_id"] = externalID
+	}
+
+	result := tx.Model(payment).Where("status = ?", models.PaymentPending).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrPaymentNotPending
+	}
+	if err := tx.First(payment, payment.ID).Error; err != nil {
+		return err
+	}
+
+	if status == models.PaymentPending {
+		return nil
+	}
+	return refreshInvoicePaid(tx, payment.InvoiceID)
+}
+
+// refreshInvoicePaid recomputes what has been paid on the invoice and marks it
+// paid once nothing is due, or issued again after a refund. Void invoices are
+// left alone.
+func refreshInvoicePaid(tx *gorm.DB, invoiceID uint) error {
+	invoice, err := lockInvoice(tx, invoiceID)
+	if err != nil {
+		return err
+	}
+
+	paid, err := paymentTotal(tx.Where("invoice_id = ? AND kind = ? AND status = ?",
+		invoiceID, models.PaymentKindPayment, models.PaymentSucceeded))
+	if err != nil {
+		return err
+	}
+	refunded, err := paymentTotal(tx.Where("invoice_id = ? AND kind = ? AND status = ?",
+		invoiceID, models.PaymentKindRefund, models.PaymentSucceeded))
+	if err != nil {
+		return err
+	}
+
+	invoice.AmountPaid = math.Round((paid-refunded)*100) / 100
+	switch {
+	case invoice.Status == models.InvoiceIssued && invoice.BalanceDue() <= 0:
+		invoice.Status = models.InvoicePaid
+	case invoice.Status == models.InvoicePaid && invoice.BalanceDue() > 0:
+		invoice.Status = models.InvoiceIssued
+	}
+
+	return tx.Model(invoice).Updates(map[string]interface{}{
+		"amount_paid": invoice.AmountPaid,
+		"status":      invoice.Status,
+	}).Error
+}
+
+// lockInvoice loads the invoice after touching its row, so payments against
+// it are serialized until the transaction ends.
+func lockInvoice(tx *gorm.DB, id uint) (*models.Invoice, error) {
+	result := tx.Model(&models.Invoice{}).Where("id = ?", id).
+		UpdateColumn("updated_at", gorm.Expr("updated_at"))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+
+	var invoice models.Invoice
+	if err := tx.First(&invoice, id).Error; err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+func paymentTotal(query *gorm.DB) (float64, error) {
+	var total float64
+	err := query.Model(&models.Payment{}).Select("COALESCE(SUM(amount), 0)").Scan(&total).Error
+	return total, err
+}
@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"rental-api/models"
+	"rental-api/payments"
+	"sync"
+	"testing"
+	"time"
+)
+
+// issueTestInvoice returns a rental of one day at 100.000 and returns it, which
+// issues an invoice of 111.000 with the default 11% PPN.
+func issueTestInvoice(t *testing.T, repos *Repositories) *models.Invoice {
+	t.Helper()
+	ctx := context.Background()
+
+	machine := models.MesinBor{Name: "Bosch GSR 18V", StockAvailability: 1, RentalCosts: 100000}
+	if err := repos.Machines.Create(ctx, &machine); err != nil {
+		t.Fatalf("failed to create machine: %v", err)
+	}
+
+	now := time.Now()
+	due := now.Add(24 * time.Hour)
+	rental := models.RentalHistory{UserID: 1, MachineID: machine.ID, RentalDate: now, DueDate: &due}
+	if err := repos.Rentals.Create(ctx, &rental); err != nil {
+		t.Fatalf("failed to create rental: %v", err)
+	}
+	for _, status := range []string{models.RentalConfirmed, models.RentalPickedUp} {
+		if _, err := repos.Rentals.Transition(ctx, int(rental.ID), status, nil, ""); err != nil {
+			t.Fatalf("failed to move rental to %s: %v", status, err)
+		}
+	}
+	if _, err := repos.Rentals.MarkAsReturned(ctx, int(rental.ID), due, nil, nil); err != nil {
+		t.Fatalf("failed to return rental: %v", err)
+	}
+
+	invoice, _, err := repos.Invoices.GenerateForRental(ctx, rental.ID)
+	if err != nil {
+		t.Fatalf("failed to get invoice: %v", err)
+	}
+	if invoice.Total != 111000 {
+		t.Fatalf("expected an invoice of 111000, got %v", invoice.Total)
+	}
+	return invoice
+}
+
+// payThroughFake records a payment taken by the fake gateway, which leaves it
+// pending until a webhook settles it, and returns it with its external ID.
+func payThroughFake(t *testing.T, repos *Repositories, gateway *payments.FakeGateway, invoiceID uint, amount float64) *models.Payment {
+	t.Helper()
+	ctx := context.Background()
+
+	payment := models.Payment{InvoiceID: invoiceID, Kind: models.PaymentKindPayment, Method: gateway.Name(), Amount: amount}
+	if err := repos.Payments.Create(ctx, &payment); err != nil {
+		t.Fatalf("failed to create payment: %v", err)
+	}
+	result, err := gateway.Charge(ctx, payments.ChargeRequest{PaymentID: payment.ID, Amount: amount, Currency: payments.Currency})
+	if err != nil {
+		t.Fatalf("failed to charge: %v", err)
+	}
+	pending, err := repos.Payments.SetStatus(ctx, payment.ID, result.Status, result.ExternalID)
+	if err != nil {
+		t.Fatalf("failed to store charge: %v", err)
+	}
+	if pending.Status != models.PaymentPending {
+		t.Fatalf("expected the fake charge to stay pending, got %s", pending.Status)
+	}
+	return pending
+}
+
+func deliverWebhook(t *testing.T, repos *Repositories, gateway *payments.FakeGateway, webhook payments.FakeWebhook) (bool, error) {
+	t.Helper()
+
+	body, header, err := gateway.SignedWebhook(webhook)
+	if err != nil {
+		t.Fatalf("failed to sign webhook: %v", err)
+	}
+	event, err := gateway.ParseWebhook(header, body)
+	if err != nil {
+		t.Fatalf("failed to parse webhook: %v", err)
+	}
+	return repos.Payments.ProcessWebhook(context.Background(), event)
+}
+
+func reloadInvoice(t *testing.T, repos *Repositories, id uint) *models.Invoice {
+	t.Helper()
+
+	invoice, err := repos.Invoices.GetByID(context.Background(), int(id))
+	if err != nil {
+		t.Fatalf("failed to reload invoice: %v", err)
+	}
+	return invoice
+}
+
+func TestWebhookReplayIsIdempotent(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+	gateway := payments.NewFakeGateway("test-secret")
+	invoice := issueTestInvoice(t, repos)
+
+	payment := payThroughFake(t, repos, gateway, invoice.ID, 50000)
+	webhook := payments.FakeWebhook{EventID: "evt_1", PaymentID: *payment.ExternalID, Status: models.PaymentSucceeded}
+
+	const deliveries = 5
+	var (
+		wg         sync.WaitGroup
+		mu         sync.Mutex
+		applied    int
+		duplicates int
+		failures   []error
+	)
+	start := make(chan struct{})
+	for i := 0; i < deliveries; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			duplicate, err := deliverWebhook(t, repos, gateway, webhook)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil:
+				failures = append(failures, err)
+			case duplicate:
+				duplicates++
+			default:
+				applied++
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if len(failures) > 0 {
+		t.Fatalf("expected every delivery to be acknowledged, got %v", failures)
+	}
+	if applied != 1 || duplicates != deliveries-1 {
+		t.Errorf("expected 1 applied and %d duplicates, got %d and %d", deliveries-1, applied, duplicates)
+	}
+	if got := reloadInvoice(t, repos, invoice.ID).AmountPaid; got != 50000 {
+		t.Errorf("expected 50000 paid, got %v", got)
+	}
+
+	// A different event for the payment that is already settled is
+	// acknowledged and changes nothing.
+	duplicate, err := deliverWebhook(t, repos, gateway, payments.FakeWebhook{EventID: "evt_2", PaymentID: *payment.ExternalID, Status: models.PaymentFailed})
+	if err != nil || duplicate {
+		t.Fatalf("expected a late event to be acknowledged, got duplicate=%v err=%v", duplicate, err)
+	}
+	settled, err := repos.Payments.GetByID(ctx, int(payment.ID))
+	if err != nil {
+		t.Fatalf("failed to reload payment: %v", err)
+	}
+	if settled.Status != models.PaymentSucceeded {
+		t.Errorf("expected payment to stay succeeded, got %s", settled.Status)
+	}
+}
+
+func TestPartialPaymentsAndRefundBalances(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+	gateway := payments.NewFakeGateway("test-secret")
+	invoice := issueTestInvoice(t, repos)
+
+	first := payThroughFake(t, repos, gateway, invoice.ID, 50000)
+	if _, err := deliverWebhook(t, repos, gateway, payments.FakeWebhook{EventID: "evt_1", PaymentID: *first.ExternalID, Status: models.PaymentSucceeded}); err != nil {
+		t.Fatalf("failed to settle first payment: %v", err)
+	}
+	partly := reloadInvoice(t, repos, invoice.ID)
+	if partly.Status != models.InvoiceIssued || partly.AmountPaid != 50000 || partly.BalanceDue() != 61000 {
+		t.Errorf("expected issued with 50000 paid and 61000 due, got %s with %v paid and %v due", partly.Status, partly.AmountPaid, partly.BalanceDue())
+	}
+
+	excess := models.Payment{InvoiceID: invoice.ID, Kind: models.PaymentKindPayment, Method: gateway.Name(), Amount: 61001}
+	if err := repos.Payments.Create(ctx, &excess); !errors.Is(err, models.ErrOverpayment) {
+		t.Errorf("expected ErrOverpayment for more than the balance due, got %v", err)
+	}
+
+	second := payThroughFake(t, repos, gateway, invoice.ID, 61000)
+	if _, err := deliverWebhook(t, repos, gateway, payments.FakeWebhook{EventID: "evt_2", PaymentID: *second.ExternalID, Status: models.PaymentSucceeded}); err != nil {
+		t.Fatalf("failed to settle second payment: %v", err)
+	}
+	if paid := reloadInvoice(t, repos, invoice.ID); paid.Status != models.InvoicePaid || paid.AmountPaid != 111000 {
+		t.Errorf("expected paid with 111000, got %s with %v", paid.Status, paid.AmountPaid)
+	}
+
+	refund := models.Payment{InvoiceID: invoice.ID, Kind: models.PaymentKindRefund, Amount: 20000, RefundOfID: &first.ID}
+	if err := repos.Payments.Create(ctx, &refund); err != nil {
+		t.Fatalf("failed to create refund: %v", err)
+	}
+	result, err := gateway.Refund(ctx, payments.RefundRequest{PaymentID: refund.ID, ExternalID: *first.ExternalID, Amount: refund.Amount})
+	if err != nil {
+		t.Fatalf("failed to refund: %v", err)
+	}
+	if _, err := repos.Payments.SetStatus(ctx, refund.ID, result.Status, result.ExternalID); err != nil {
+		t.Fatalf("failed to store refund: %v", err)
+	}
+
+	refunded := reloadInvoice(t, repos, invoice.ID)
+	if refunded.Status != models.InvoiceIssued || refunded.AmountPaid != 91000 || refunded.BalanceDue() != 20000 {
+		t.Errorf("expected issued with 91000 paid and 20000 due, got %s with %v paid and %v due", refunded.Status, refunded.AmountPaid, refunded.BalanceDue())
+	}
+
+	tooMuch := models.Payment{InvoiceID: invoice.ID, Kind: models.PaymentKindRefund, Amount: 30001, RefundOfID: &first.ID}
+	if err := repos.Payments.Create(ctx, &tooMuch); !errors.Is(err, models.ErrRefundTooLarge) {
+		t.Errorf("expected ErrRefundTooLarge for more than is left of the payment, got %v", err)
+	}
+
+	balance, err := repos.Payments.Balance(ctx, invoice.UserID)
+	if err != nil {
+		t.Fatalf("failed to get balance: %v", err)
+	}
+	want := models.AccountBalance{UserID: invoice.UserID, Invoiced: 111000, Paid: 111000, Refunded: 20000, Outstanding: 20000}
+	if *balance != want {
+		t.Errorf("expected balance %+v, got %+v", want, *balance)
+	}
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// Page selects a slice of a listing. Sort names a field, prefixed with "-"
+// for descending order; an empty Sort uses the listing's default.
+type Page struct {
+	Limit  int
+	Offset int
+	Sort   string
+}
+
+// PageInfo describes the slice a listing returned.
+type PageInfo struct {
+	Total  int64
+	Limit  int
+	Offset int
+	Sort   string
+}
+
+// sortFields whitelists the fields a listing can be sorted on and maps them to
+// columns.
+type sortFields struct {
+	columns  map[string]string
+	fallback string
+}
+
+// order returns the ORDER BY clause for sort, breaking ties on id so pages do
+// not overlap.
+func (s sortFields) order(sort string) (string, string, error) {
+	if sort == "" {
+		sort = s.fallback
+	}
+	direction := "ASC"
+	field := sort
+	if strings.HasPrefix(sort, "-") {
+		direction = "DESC"
+		field = sort[1:]
+	}
+
+	column, ok := s.columns[field]
+	if !ok {
+		return "", "", ErrInvalidSort
+	}
+	if column == "id" {
+		return "id " + direction, sort, nil
+	}
+	return column + " " + direction + ", id " + direction, sort, nil
+}
+
+func (p Page) normalized() Page {
+	if p.Limit <= 0 {
+		p.Limit = DefaultPageSize
+	}
+	if p.Limit > MaxPageSize {
+		p.Limit = MaxPageSize
+	}
+	if p.Offset < 0 {
+		p.Offset = 0
+	}
+	return p
+}
+
+// paginate counts what query matches and loads the requested page of it into
+// dest. Preloads are applied to the page only.
+func paginate(query *gorm.DB, page Page, sorts sortFields, dest interface{}, preloads ...string) (*PageInfo, error) {
+	page = page.normalized()
+	order, sort, err := sorts.order(page.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	find := query.Order(order).Limit(page.Limit).Offset(page.Offset)
+	for _, preload := range preloads {
+		find = find.Preload(preload)
+	}
+	if err := find.Find(dest).Error; err != nil {
+		return nil, err
+	}
+
+	return &PageInfo{Total: total, Limit: page.Limit, Offset: page.Offset, Sort: sort}, nil
+}
+
+// DateRange bounds a timestamp column. From is inclusive and To exclusive;
+// either may be nil.
+type DateRange struct {
+	From *time.Time
+	To   *time.Time
+}
+
+func (r DateRange) apply(query *gorm.DB, column string) *gorm.DB {
+	if r.From != nil {
+		query = query.Where(column+" >= ?", *r.From)
+	}
+	if r.To != nil {
+		query = query.Where(column+" < ?", *r.To)
+	}
+	return query
+}
+
+type ReviewFilter struct {
+	MachineID *uint
+	UserID    *uint
+	MinRating *int
+	MaxRating *int
+	Created   DateRange
+}
+
+type RentalFilter struct {
+	MachineID *uint
+	UserID    *uint
+	Status    string
+	Rented    DateRange
+}
+
+type MaintenanceFilter struct {
+	MachineID *uint
+	Fixed     *bool
+	Created   DateRange
+}
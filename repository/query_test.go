@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"rental-api/models"
+	"testing"
+	"time"
+)
+
+func TestPageNormalized(t *testing.T) {
+	tests := []struct {
+		name string
+		page Page
+		want Page
+	}{
+		{name: "defaults", page: Page{}, want: Page{Limit: DefaultPageSize}},
+		{name: "negative limit", page: Page{Limit: -5}, want: Page{Limit: DefaultPageSize}},
+		{name: "within range", page: Page{Limit: 30, Offset: 60}, want: Page{Limit: 30, Offset: 60}},
+		{name: "at maximum", page: Page{Limit: MaxPageSize}, want: Page{Limit: MaxPageSize}},
+		{name: "above maximum", page: Page{Limit: MaxPageSize + 1}, want: Page{Limit: MaxPageSize}},
+		{name: "negative offset", page: Page{Limit: 10, Offset: -1}, want: Page{Limit: 10}},
+		{name: "sort kept", page: Page{Sort: "-id"}, want: Page{Limit: DefaultPageSize, Sort: "-id"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.page.normalized(); got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSortFieldsOrder(t *testing.T) {
+	sorts := sortFields{
+		fallback: "-created",
+		columns:  map[string]string{"id": "id", "created": "created_at", "rating": "rating"},
+	}
+
+	tests := []struct {
+		name  string
+		sort  string
+		order string
+		used  string
+		err   error
+	}{
+		{name: "fallback", sort: "", order: "created_at DESC, id DESC", used: "-created"},
+		{name: "ascending", sort: "rating", order: "rating ASC, id ASC", used: "rating"},
+		{name: "descending", sort: "-rating", order: "rating DESC, id DESC", used: "-rating"},
+		{name: "mapped to column", sort: "created", order: "created_at ASC, id ASC", used: "created"},
+		{name: "id needs no tie break", sort: "-id", order: "id DESC", used: "-id"},
+		{name: "column name not exposed", sort: "created_at", err: ErrInvalidSort},
+		{name: "unknown field", sort: "password", err: ErrInvalidSort},
+		{name: "bare minus", sort: "-", err: ErrInvalidSort},
+		{name: "double minus", sort: "--rating", err: ErrInvalidSort},
+		{name: "case sensitive", sort: "Rating", err: ErrInvalidSort},
+		{name: "injection", sort: "rating; DROP TABLE users", err: ErrInvalidSort},
+		{name: "raw direction", sort: "rating desc", err: ErrInvalidSort},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, used, err := sorts.order(tt.sort)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if order != tt.order || used != tt.used {
+				t.Errorf("expected %q sorted as %q, got %q sorted as %q", tt.used, tt.order, used, order)
+			}
+		})
+	}
+}
+
+func TestListPaginates(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+
+	machine := models.MesinBor{Name: "Bosch GSB 13 RE", StockAvailability: 5, RentalCosts: 20000}
+	if err := repos.Machines.Create(ctx, &machine); err != nil {
+		t.Fatalf("failed to create machine: %v", err)
+	}
+	start := time.Now().Add(-24 * time.Hour)
+	for i := 0; i < 5; i++ {
+		rental := models.RentalHistory{UserID: 1, MachineID: machine.ID, RentalDate: start.Add(time.Duration(i) * time.Hour)}
+		if err := repos.Rentals.Create(ctx, &rental); err != nil {
+			t.Fatalf("failed to create rental: %v", err)
+		}
+	}
+
+	rentals, info, err := repos.Rentals.List(ctx, RentalFilter{}, Page{Limit: 2, Offset: 1, Sort: "rental_date"})
+	if err != nil {
+		t.Fatalf("failed to list rentals: %v", err)
+	}
+	if len(rentals) != 2 || info.Total != 5 {
+		t.Fatalf("expected 2 of 5 rentals, got %d of %d", len(rentals), info.Total)
+	}
+	if !rentals[0].RentalDate.Equal(start.Add(time.Hour)) || !rentals[1].RentalDate.Equal(start.Add(2*time.Hour)) {
+		t.Errorf("expected the second and third rentals, got %v and %v", rentals[0].RentalDate, rentals[1].RentalDate)
+	}
+
+	rentals, info, err = repos.Rentals.List(ctx, RentalFilter{}, Page{Limit: MaxPageSize * 10, Offset: 4})
+	if err != nil {
+		t.Fatalf("failed to list rentals: %v", err)
+	}
+	if info.Limit != MaxPageSize || info.Offset != 4 || info.Sort != "-rental_date" {
+		t.Errorf("expected limit %d, offset 4 and sort -rental_date, got %+v", MaxPageSize, *info)
+	}
+	if len(rentals) != 1 || !rentals[0].RentalDate.Equal(start) {
+		t.Errorf("expected only the oldest rental past offset 4, got %d rentals", len(rentals))
+	}
+
+	rentals, info, err = repos.Rentals.List(ctx, RentalFilter{}, Page{Offset: 10})
+	if err != nil {
+		t.Fatalf("failed to list rentals: %v", err)
+	}
+	if len(rentals) != 0 || info.Total != 5 {
+		t.Errorf("expected an empty page of 5 rentals, got %d of %d", len(rentals), info.Total)
+	}
+
+	if _, _, err := repos.Rentals.List(ctx, RentalFilter{}, Page{Sort: "user_id"}); !errors.Is(err, ErrInvalidSort) {
+		t.Errorf("expected ErrInvalidSort for a field outside the allowlist, got %v", err)
+	}
+}
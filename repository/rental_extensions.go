@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"math"
+	"rental-api/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Extend moves the rental's due date to newDueDate, reprices it over the
+// whole new period and tops up the deposit hold to match. The extra days
+// must not collide with reservations or other rentals of the same machine;
+// when they do it fails with a *models.BookingConflictError.
+func (r *gormRentalRepository) Extend(ctx context.Context, id int, newDueDate time.Time, actorID *uint, note string) (*models.RentalHistory, *models.RentalExtension, error) {
+	var rental models.RentalHistory
+	var extension models.RentalExtension
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		if err := tx.First(&rental, id).Error; err != nil {
+			return err
+		}
+
+		machine, err := lockMachine(tx, rental.MachineID)
+		if err != nil {
+			return err
+		}
+
+		// Re-read under the machine lock so a concurrent extension or
+		// transition is not overwritten.
+		if err := tx.First(&rental, id).Error; err != nil {
+			return err
+		}
+		if !models.RentalCanExtend(rental.Status) || rental.DueDate == nil {
+			return models.ErrRentalNotExtendable
+		}
+		previousDue := *rental.DueDate
+		if !newDueDate.After(previousDue) {
+			return models.ErrExtensionTooShort
+		}
+
+		self := models.Booking{Type: models.BookingRental, ID: rental.ID}
+		if err := checkCapacity(tx, machine, previousDue, newDueDate, &self); err != nil {
+			return err
+		}
+
+		previousCost := rental.TotalCost
+		rental.DueDate = &newDueDate
+		models.PriceRental(machine, &rental)
+
+		if err := tx.Model(&rental).
+			Select("due_date", "total_cost", "cost_breakdown").
+			Updates(&rental).Error; err != nil {
+			return err
+		}
+		if err := topUpDeposit(tx, &rental, machine, actorID); err != nil {
+			return err
+		}
+
+		if rental.OrderID != nil {
+			if err := refreshOrder(tx, *rental.OrderID); err != nil {
+				return err
+			}
+		}
+
+		extension = models.RentalExtension{
+			RentalID:        rental.ID,
+			PreviousDueDate: previousDue,
+			NewDueDate:      newDueDate,
+			PreviousCost:    previousCost,
+			AdditionalCost:  math.Round((rental.TotalCost-previousCost)*100) / 100,
+			ActorID:         actorID,
+			Note:            note,
+		}
+		return tx.Create(&extension).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &rental, &extension, nil
+}
+
+func (r *gormRentalRepository) Extensions(ctx context.Context, rentalID uint) ([]models.RentalExtension, error) {
+	var extensions []models.RentalExtension
+	if err := conn(ctx, r.db).Where("rental_id = ?", rentalID).Order("created_at, id").Find(&extensions).Error; err != nil {
+		return nil, err
+	}
+	return extensions, nil
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"rental-api/models"
+	"testing"
+	"time"
+)
+
+func TestExtendReprices(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+
+	machine := models.MesinBor{Name: "Bosch GSB 18V", StockAvailability: 1, RentalCosts: 50000, WeeklyRate: 250000}
+	if err := repos.Machines.Create(ctx, &machine); err != nil {
+		t.Fatalf("failed to create machine: %v", err)
+	}
+
+	start := time.Now()
+	due := start.Add(2 * 24 * time.Hour)
+	rental := models.RentalHistory{UserID: 1, MachineID: machine.ID, RentalDate: start, DueDate: &due}
+	if err := repos.Rentals.Create(ctx, &rental); err != nil {
+		t.Fatalf("failed to create rental: %v", err)
+	}
+	if rental.TotalCost != 100000 {
+		t.Fatalf("expected two days at 100000, got %v", rental.TotalCost)
+	}
+
+	if _, _, err := repos.Rentals.Extend(ctx, int(rental.ID), due, nil, ""); !errors.Is(err, models.ErrExtensionTooShort) {
+		t.Errorf("expected ErrExtensionTooShort for the same due date, got %v", err)
+	}
+
+	actorID := uint(9)
+	fourDays := start.Add(4 * 24 * time.Hour)
+	extended, extension, err := repos.Rentals.Extend(ctx, int(rental.ID), fourDays, &actorID, "Job overran")
+	if err != nil {
+		t.Fatalf("failed to extend rental: %v", err)
+	}
+	if !extended.DueDate.Equal(fourDays) || extended.TotalCost != 200000 {
+		t.Errorf("expected due %v at 200000, got %v at %v", fourDays, extended.DueDate, extended.TotalCost)
+	}
+	if !extension.PreviousDueDate.Equal(due) || extension.PreviousCost != 100000 || extension.AdditionalCost != 100000 {
+		t.Errorf("unexpected extension record %+v", extension)
+	}
+
+	// Six days cost more than a week, so the week rate applies.
+	extended, extension, err = repos.Rentals.Extend(ctx, int(rental.ID), start.Add(6*24*time.Hour), &actorID, "")
+	if err != nil {
+		t.Fatalf("failed to extend rental again: %v", err)
+	}
+	if extended.TotalCost != 250000 || extension.AdditionalCost != 50000 {
+		t.Errorf("expected a week at 250000 adding 50000, got %v adding %v", extended.TotalCost, extension.AdditionalCost)
+	}
+
+	stored, err := repos.Rentals.GetByID(ctx, int(rental.ID))
+	if err != nil {
+		t.Fatalf("failed to reload rental: %v", err)
+	}
+	if stored.TotalCost != 250000 || stored.CostBreakdown == nil || stored.CostBreakdown.BilledDays != 6 {
+		t.Errorf("expected the new price to be stored, got %v", stored.TotalCost)
+	}
+	extensions, err := repos.Rentals.Extensions(ctx, rental.ID)
+	if err != nil {
+		t.Fatalf("failed to list extensions: %v", err)
+	}
+	if len(extensions) != 2 {
+		t.Errorf("expected 2 extensions, got %d", len(extensions))
+	}
+}
+
+func TestExtendConflictsWithReservation(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+	machine := createTestMachine(t, repos, "Makita DHP482", 1)
+
+	start := time.Now()
+	due := start.Add(2 * 24 * time.Hour)
+	rental := models.RentalHistory{UserID: 1, MachineID: machine.ID, RentalDate: start, DueDate: &due}
+	if err := repos.Rentals.Create(ctx, &rental); err != nil {
+		t.Fatalf("failed to create rental: %v", err)
+	}
+
+	reserved := due.Add(24 * time.Hour)
+	reservation := models.Reservation{UserID: 2, MachineID: machine.ID, StartDate: reserved, EndDate: reserved.Add(24 * time.Hour)}
+	if err := repos.Reservations.Create(ctx, &reservation); err != nil {
+		t.Fatalf("failed to create reservation: %v", err)
+	}
+
+	_, _, err := repos.Rentals.Extend(ctx, int(rental.ID), reserved.Add(time.Hour), nil, "")
+	var conflict *models.BookingConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected a BookingConflictError, got %v", err)
+	}
+	if len(conflict.Conflicts) != 1 || conflict.Conflicts[0].Type != models.BookingReservation {
+		t.Errorf("expected the reservation as the only conflict, got %+v", conflict.Conflicts)
+	}
+
+	stored, err := repos.Rentals.GetByID(ctx, int(rental.ID))
+	if err != nil {
+		t.Fatalf("failed to reload rental: %v", err)
+	}
+	if !stored.DueDate.Equal(due) {
+		t.Errorf("expected the due date to stay %v, got %v", due, stored.DueDate)
+	}
+
+	if _, _, err := repos.Rentals.Extend(ctx, int(rental.ID), reserved, nil, ""); err != nil {
+		t.Errorf("expected an extension up to the reservation to fit, got %v", err)
+	}
+}
+
+func TestExtendRejectsClosedRentals(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+	machine := createTestMachine(t, repos, "Hilti SF 6H", 10)
+
+	rent := func(t *testing.T, due *time.Time, path ...string) *models.RentalHistory {
+		t.Helper()
+		rental := models.RentalHistory{UserID: 1, MachineID: machine.ID, RentalDate: time.Now().Add(-5 * 24 * time.Hour), DueDate: due}
+		if err := repos.Rentals.Create(ctx, &rental); err != nil {
+			t.Fatalf("failed to create rental: %v", err)
+		}
+		for _, status := range path {
+			if _, err := repos.Rentals.Transition(ctx, int(rental.ID), status, nil, ""); err != nil {
+				t.Fatalf("failed to move rental to %s: %v", status, err)
+			}
+		}
+		return &rental
+	}
+	past := time.Now().Add(-24 * time.Hour)
+	future := time.Now().Add(24 * time.Hour)
+
+	tests := []struct {
+		name   string
+		rental func(t *testing.T) *models.RentalHistory
+	}{
+		{name: "cancelled", rental: func(t *testing.T) *models.RentalHistory {
+			return rent(t, &future, models.RentalCancelled)
+		}},
+		{name: "returned", rental: func(t *testing.T) *models.RentalHistory {
+			rental := rent(t, &future, models.RentalConfirmed, models.RentalPickedUp)
+			if _, err := repos.Rentals.MarkAsReturned(ctx, int(rental.ID), time.Now(), nil, nil); err != nil {
+				t.Fatalf("failed to return rental: %v", err)
+			}
+			return rental
+		}},
+		{name: "overdue", rental: func(t *testing.T) *models.RentalHistory {
+			return rent(t, &past, models.RentalConfirmed, models.RentalPickedUp, models.RentalOverdue)
+		}},
+		{name: "no due date", rental: func(t *testing.T) *models.RentalHistory {
+			return rent(t, nil, models.RentalConfirmed)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rental := tt.rental(t)
+			newDue := time.Now().Add(7 * 24 * time.Hour)
+			if _, _, err := repos.Rentals.Extend(ctx, int(rental.ID), newDue, nil, ""); !errors.Is(err, models.ErrRentalNotExtendable) {
+				t.Fatalf("expected ErrRentalNotExtendable, got %v", err)
+			}
+			extensions, err := repos.Rentals.Extensions(ctx, rental.ID)
+			if err != nil {
+				t.Fatalf("failed to list extensions: %v", err)
+			}
+			if len(extensions) != 0 {
+				t.Errorf("expected no extension to be recorded, got %d", len(extensions))
+			}
+		})
+	}
+}
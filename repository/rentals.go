@@ -0,0 +1,326 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"rental-api/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type gormRentalRepository struct {
+	db *gorm.DB
+}
+
+// Create reserves one unit of the machine, inserts the rental in the requested
+// status and puts the deposit on hold, all in the same transaction.
+func (r *gormRentalRepository) Create(ctx context.Context, rental *models.RentalHistory) error {
+	return transaction(ctx, r.db, func(tx *gorm.DB) error {
+		return createRental(tx, rental, nil)
+	})
+}
+
+func (r *gormRentalRepository) GetByID(ctx context.Context, id int) (*models.RentalHistory, error) {
+	var rental models.RentalHistory
+	if err := conn(ctx, r.db).First(&rental, id).Error; err != nil {
+		return nil, err
+	}
+	return &rental, nil
+}
+
+var rentalSorts = sortFields{
+	fallback: "-rental_date",
+	columns: map[string]string{
+		"id":          "id",
+		"created_at":  "created_at",
+		"rental_date": "rental_date",
+		"due_date":    "due_date",
+		"return_date": "return_date",
+		"total_cost":  "total_cost",
+		"status":      "status",
+	},
+}
+
+// List returns a page of the rentals matching filter.
+func (r *gormRentalRepository) List(ctx context.Context, filter RentalFilter, page Page) ([]models.RentalHistory, *PageInfo, error) {
+	query := conn(ctx, r.db).Model(&models.RentalHistory{})
+	if filter.MachineID != nil {
+		query = query.Where("machine_id = ?", *filter.MachineID)
+	}
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	query = filter.Rented.apply(query, "rental_date")
+
+	var rentals []models.RentalHistory
+	info, err := paginate(query, page, rentalSorts, &rentals)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rentals, info, nil
+}
+
+// Transition moves the rental to the given status and records who did it.
+// Moves the state machine does not allow fail with a
+// *models.InvalidTransitionError.
+func (r *gormRentalRepository) Transition(ctx context.Context, id int, to string, actorID *uint, note string) (*models.RentalHistory, error) {
+	var rental models.RentalHistory
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		if err := tx.First(&rental, id).Error; err != nil {
+			return err
+		}
+		return transitionRental(tx, &rental, to, actorID, note)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rental, nil
+}
+
+// MarkAsReturned closes the rental, reprices it on the actual period, settles
+// any late fee, puts the unit back in stock and issues the invoice. When an inspection is given it
+// is recorded, the machine takes on the inspected condition and a maintenance
+// record is opened unless the machine came back Good. The late fee and the
+// damage charge are forfeited from the deposit and the rest of it is released.
+// Returning a rental twice fails with models.ErrAlreadyReturned.
+func (r *gormRentalRepository) MarkAsReturned(ctx context.Context, id int, returnDate time.Time, actorID *uint, inspection *models.ReturnInspection) (*models.RentalHistory, error) {
+	if inspection != nil {
+		if err := inspection.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	var rental models.RentalHistory
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		if err := tx.First(&rental, id).Error; err != nil {
+			return err
+		}
+		return returnRental(tx, &rental, returnDate, actorID, inspection)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rental, nil
+}
+
+// Inspection returns the rental's return inspection, or nil when it was
+// returned without one or is still out.
+func (r *gormRentalRepository) Inspection(ctx context.Context, rentalID uint) (*models.ReturnInspection, error) {
+	var inspections []models.ReturnInspection
+	if err := conn(ctx, r.db).Where("rental_id = ?", rentalID).Limit(1).Find(&inspections).Error; err != nil {
+		return nil, err
+	}
+	if len(inspections) == 0 {
+		return nil, nil
+	}
+	return &inspections[0], nil
+}
+
+func (r *gormRentalRepository) Events(ctx context.Context, rentalID uint) ([]models.RentalStatusEvent, error) {
+	var events []models.RentalStatusEvent
+	if err := conn(ctx, r.db).Where("rental_id = ?", rentalID).Order("created_at, id").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// createRental reserves one unit of the machine and inserts the rental. The
+// machine stays locked until the transaction ends, so concurrent rentals can
+// never take the stock below zero. The unit must also be free of reservations
+// and maintenance for the rental period, which runs to the due date or, when
+// there is none, indefinitely; otherwise it fails with a
+// *models.BookingConflictError. The rental joins line's order, or no order
+// when line is nil; order links and charges set by the caller are discarded.
+func createRental(tx *gorm.DB, rental *models.RentalHistory, line *models.RentalOrderLine) error {
+	rental.ID = 0
+	rental.OrderID, rental.OrderLineID = nil, nil
+	if line != nil {
+		orderID, lineID := line.OrderID, line.ID
+		rental.OrderID, rental.OrderLineID = &orderID, &lineID
+	}
+	rental.ReturnDate = nil
+	rental.LateFee, rental.OverdueDays, rental.DamageCharge = 0, 0, 0
+
+	machine, err := lockMachine(tx, rental.MachineID)
+	if err != nil {
+		return err
+	}
+	if machine.StockAvailability <= 0 {
+		return models.ErrOutOfStock
+	}
+
+	end := models.OpenEnd
+	if rental.DueDate != nil {
+		end = *rental.DueDate
+	}
+	if err := checkCapacity(tx, machine, rental.RentalDate, end, nil); err != nil {
+		return err
+	}
+
+	result := tx.Model(machine).
+		Where("stock_availability > 0").
+		UpdateColumn("stock_availability", gorm.Expr("stock_availability - 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrOutOfStock
+	}
+
+	models.PriceRental(machine, rental)
+	rental.Status = models.RentalRequested
+
+	if err := tx.Create(rental).Error; err != nil {
+		return err
+	}
+
+	actorID := rental.UserID
+	if err := tx.Create(&models.RentalStatusEvent{
+		RentalID: rental.ID,
+		ToStatus: models.RentalRequested,
+		ActorID:  &actorID,
+	}).Error; err != nil {
+		return err
+	}
+
+	return holdDeposit(tx, rental, machine)
+}
+
+// returnRental does the work of MarkAsReturned on a rental loaded in tx.
+func returnRental(tx *gorm.DB, rental *models.RentalHistory, returnDate time.Time, actorID *uint, inspection *models.ReturnInspection) error {
+	if rental.Status == models.RentalReturned {
+		return models.ErrAlreadyReturned
+	}
+
+	var machine models.MesinBor
+	if err := tx.Unscoped().First(&machine, rental.MachineID).Error; err != nil {
+		return err
+	}
+	rental.ReturnDate = &returnDate
+	models.PriceRental(&machine, rental)
+	if err := applyLateFee(tx, rental, &machine, returnDate); err != nil {
+		return err
+	}
+	if inspection != nil {
+		rental.DamageCharge = inspection.DamageCharge
+	}
+
+	if err := transitionRental(tx, rental, models.RentalReturned, actorID, "",
+		"return_date", "total_cost", "cost_breakdown", "late_fee", "overdue_days", "damage_charge"); err != nil {
+		return err
+	}
+
+	var charges []models.DepositCharge
+	if rental.LateFee > 0 {
+		charges = append(charges, models.DepositCharge{
+			Amount: rental.LateFee,
+			Reason: fmt.Sprintf("Late return: %d days overdue", rental.OverdueDays),
+		})
+	}
+	if inspection != nil {
+		if err := recordInspection(tx, rental, &machine, inspection, actorID); err != nil {
+			return err
+		}
+		if inspection.DamageCharge > 0 {
+			charges = append(charges, models.DepositCharge{
+				Amount: inspection.DamageCharge,
+				Reason: "Damage: " + inspection.Summary(),
+			})
+		}
+	}
+	if err := settleDeposit(tx, rental, charges, actorID); err != nil {
+		return err
+	}
+
+	// Rentals that belong to an order are invoiced with the order.
+	if rental.OrderID == nil {
+		if _, _, err := invoiceRental(tx, rental); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transitionRental saves the new status together with any extra columns,
+// records the event and releases the unit when the rental stops holding it.
+// Cancelling also releases the deposit. The rental's order, if any, is kept
+// in step.
+// The status update is conditional on the old status so that two concurrent
+// transitions of the same rental cannot both succeed.
+func transitionRental(tx *gorm.DB, rental *models.RentalHistory, to string, actorID *uint, note string, columns ...string) error {
+	from := rental.Status
+	if !models.CanTransitionRental(from, to) {
+		return &models.InvalidTransitionError{From: from, To: to}
+	}
+
+	rental.Status = to
+	result := tx.Model(rental).
+		Where("status = ?", from).
+		Select(append([]string{"status"}, columns...)).
+		Updates(rental)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &models.InvalidTransitionError{From: from, To: to}
+	}
+
+	if err := tx.Create(&models.RentalStatusEvent{
+		RentalID:   rental.ID,
+		FromStatus: from,
+		ToStatus:   to,
+		ActorID:    actorID,
+		Note:       note,
+	}).Error; err != nil {
+		return err
+	}
+
+	if to == models.RentalCancelled {
+		if err := settleDeposit(tx, rental, nil, actorID); err != nil {
+			return err
+		}
+	}
+
+	if models.RentalHoldsStock(from) && !models.RentalHoldsStock(to) {
+		if err := tx.Model(&models.MesinBor{}).
+			Where("id = ?", rental.MachineID).
+			UpdateColumn("stock_availability", gorm.Expr("stock_availability + 1")).Error; err != nil {
+			return err
+		}
+	}
+
+	if rental.OrderID != nil {
+		return refreshOrder(tx, *rental.OrderID)
+	}
+	return nil
+}
+
+// recordInspection saves the inspection, sets the machine's condition from it
+// and opens a maintenance record when the machine did not come back Good.
+func recordInspection(tx *gorm.DB, rental *models.RentalHistory, machine *models.MesinBor, inspection *models.ReturnInspection, actorID *uint) error {
+	inspection.RentalID = rental.ID
+	inspection.InspectorID = actorID
+
+	if err := tx.Model(machine).UpdateColumn("condition", inspection.Condition).Error; err != nil {
+		return err
+	}
+
+	if inspection.Condition != models.ConditionGood {
+		maintenance := models.Maintenance{MachineID: machine.ID, Issue: inspection.Summary()}
+		if err := tx.Create(&maintenance).Error; err != nil {
+			return err
+		}
+		inspection.MaintenanceID = &maintenance.ID
+	}
+
+	return tx.Create(inspection).Error
+}
+
+// openRentals limits a query to rentals that still hold a unit of stock.
+func openRentals(tx *gorm.DB) *gorm.DB {
+	return tx.Where("status NOT IN ?", []string{models.RentalReturned, models.RentalCancelled})
+}
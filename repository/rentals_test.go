@@ -0,0 +1,355 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"rental-api/migrations"
+	"rental-api/models"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupTestRepositories(t *testing.T) *Repositories {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_busy_timeout=10000&_journal_mode=WAL&_txlock=immediate"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	return New(db)
+}
+
+func TestCreateRentalConcurrentStock(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+
+	const stock = 5
+	const clients = 40
+
+	machine := models.MesinBor{Name: "Bosch GBH 2-26", StockAvailability: stock, RentalCosts: 50000}
+	if err := repos.Machines.Create(ctx, &machine); err != nil {
+		t.Fatalf("failed to create machine: %v", err)
+	}
+
+	var (
+		wg         sync.WaitGroup
+		mu         sync.Mutex
+		succeeded  int
+		outOfStock int
+		unexpected []error
+	)
+	start := make(chan struct{})
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(userID uint) {
+			defer wg.Done()
+			<-start
+
+			rental := models.RentalHistory{UserID: userID, MachineID: machine.ID, RentalDate: time.Now()}
+			err := repos.Rentals.Create(ctx, &rental)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+			case errors.Is(err, models.ErrOutOfStock):
+				outOfStock++
+			default:
+				unexpected = append(unexpected, err)
+			}
+		}(uint(i + 1))
+	}
+	close(start)
+	wg.Wait()
+
+	if len(unexpected) > 0 {
+		t.Fatalf("unexpected errors: %v", unexpected)
+	}
+	if succeeded != stock {
+		t.Errorf("expected %d successful rentals, got %d", stock, succeeded)
+	}
+	if outOfStock != clients-stock {
+		t.Errorf("expected %d out-of-stock rejections, got %d", clients-stock, outOfStock)
+	}
+
+	updated, err := repos.Machines.GetByID(ctx, int(machine.ID))
+	if err != nil {
+		t.Fatalf("failed to reload machine: %v", err)
+	}
+	if updated.StockAvailability != 0 {
+		t.Errorf("expected stock 0, got %d", updated.StockAvailability)
+	}
+
+	rentals, _, err := repos.Rentals.List(ctx, RentalFilter{}, Page{})
+	if err != nil {
+		t.Fatalf("failed to list rentals: %v", err)
+	}
+	if len(rentals) != stock {
+		t.Errorf("expected %d rental rows, got %d", stock, len(rentals))
+	}
+}
+
+func TestMarkAsReturnedRestoresStock(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+
+	machine := models.MesinBor{Name: "Makita HR2470", StockAvailability: 1, RentalCosts: 45000}
+	if err := repos.Machines.Create(ctx, &machine); err != nil {
+		t.Fatalf("failed to create machine: %v", err)
+	}
+
+	rental := models.RentalHistory{UserID: 1, MachineID: machine.ID, RentalDate: time.Now()}
+	if err := repos.Rentals.Create(ctx, &rental); err != nil {
+		t.Fatalf("failed to create rental: %v", err)
+	}
+	err := repos.Rentals.Create(ctx, &models.RentalHistory{UserID: 2, MachineID: machine.ID, RentalDate: time.Now()})
+	if !errors.Is(err, models.ErrOutOfStock) {
+		t.Fatalf("expected ErrOutOfStock, got %v", err)
+	}
+
+	if _, err := repos.Rentals.MarkAsReturned(ctx, int(rental.ID), time.Now(), nil, nil); !errors.Is(err, models.ErrInvalidTransition) {
+		t.Fatalf("expected ErrInvalidTransition when returning a requested rental, got %v", err)
+	}
+	for _, status := range []string{models.RentalConfirmed, models.RentalPickedUp} {
+		if _, err := repos.Rentals.Transition(ctx, int(rental.ID), status, nil, ""); err != nil {
+			t.Fatalf("failed to move rental to %s: %v", status, err)
+		}
+	}
+
+	if _, err := repos.Rentals.MarkAsReturned(ctx, int(rental.ID), time.Now(), nil, nil); err != nil {
+		t.Fatalf("failed to return rental: %v", err)
+	}
+	if _, err := repos.Rentals.MarkAsReturned(ctx, int(rental.ID), time.Now(), nil, nil); !errors.Is(err, models.ErrAlreadyReturned) {
+		t.Fatalf("expected ErrAlreadyReturned on second return, got %v", err)
+	}
+
+	updated, err := repos.Machines.GetByID(ctx, int(machine.ID))
+	if err != nil {
+		t.Fatalf("failed to reload machine: %v", err)
+	}
+	if updated.StockAvailability != 1 {
+		t.Errorf("expected stock 1 after return, got %d", updated.StockAvailability)
+	}
+}
+
+func TestCreateRentalUnknownMachine(t *testing.T) {
+	repos := setupTestRepositories(t)
+
+	err := repos.Rentals.Create(context.Background(), &models.RentalHistory{UserID: 1, MachineID: 999, RentalDate: time.Now()})
+	if !errors.Is(err, models.ErrMachineNotFound) {
+		t.Fatalf("expected ErrMachineNotFound, got %v", err)
+	}
+}
+
+func TestCreateRentalDiscardsOrderAndCharges(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+
+	machine := models.MesinBor{Name: "Hilti TE 30", StockAvailability: 1, RentalCosts: 60000}
+	if err := repos.Machines.Create(ctx, &machine); err != nil {
+		t.Fatalf("failed to create machine: %v", err)
+	}
+
+	orderID, lineID := uint(42), uint(7)
+	rental := models.RentalHistory{
+		ID:           1000,
+		UserID:       1,
+		MachineID:    machine.ID,
+		OrderID:      &orderID,
+		OrderLineID:  &lineID,
+		RentalDate:   time.Now(),
+		LateFee:      -500000,
+		OverdueDays:  3,
+		DamageCharge: -100000,
+	}
+	if err := repos.Rentals.Create(ctx, &rental); err != nil {
+		t.Fatalf("failed to create rental: %v", err)
+	}
+
+	stored, err := repos.Rentals.GetByID(ctx, int(rental.ID))
+	if err != nil {
+		t.Fatalf("failed to reload rental: %v", err)
+	}
+	if stored.ID == 1000 {
+		t.Errorf("expected a generated ID, got the client's")
+	}
+	if stored.OrderID != nil || stored.OrderLineID != nil {
+		t.Errorf("expected no order link, got order %v line %v", stored.OrderID, stored.OrderLineID)
+	}
+	if stored.LateFee != 0 || stored.OverdueDays != 0 || stored.DamageCharge != 0 {
+		t.Errorf("expected no charges, got late fee %v, %d overdue days, damage %v", stored.LateFee, stored.OverdueDays, stored.DamageCharge)
+	}
+}
+
+func TestTransitionRejectsInvalidMoves(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+
+	machine := models.MesinBor{Name: "DeWalt D25133", StockAvailability: 10, RentalCosts: 40000}
+	if err := repos.Machines.Create(ctx, &machine); err != nil {
+		t.Fatalf("failed to create machine: %v", err)
+	}
+
+	// newRental creates a rental and walks it through the given statuses.
+	newRental := func(t *testing.T, path ...string) *models.RentalHistory {
+		t.Helper()
+		rental := models.RentalHistory{UserID: 1, MachineID: machine.ID, RentalDate: time.Now()}
+		if err := repos.Rentals.Create(ctx, &rental); err != nil {
+			t.Fatalf("failed to create rental: %v", err)
+		}
+		for _, status := range path {
+			if _, err := repos.Rentals.Transition(ctx, int(rental.ID), status, nil, ""); err != nil {
+				t.Fatalf("failed to move rental to %s: %v", status, err)
+			}
+		}
+		return &rental
+	}
+
+	tests := []struct {
+		name string
+		path []string
+		from string
+		to   string
+	}{
+		{name: "pick up before confirming", from: models.RentalRequested, to: models.RentalPickedUp},
+		{name: "return before pick up", path: []string{models.RentalConfirmed}, from: models.RentalConfirmed, to: models.RentalReturned},
+		{name: "cancel once picked up", path: []string{models.RentalConfirmed, models.RentalPickedUp}, from: models.RentalPickedUp, to: models.RentalCancelled},
+		{name: "back from overdue", path: []string{models.RentalConfirmed, models.RentalPickedUp, models.RentalOverdue}, from: models.RentalOverdue, to: models.RentalActive},
+		{name: "reopen cancelled", path: []string{models.RentalCancelled}, from: models.RentalCancelled, to: models.RentalConfirmed},
+		{name: "same status", path: []string{models.RentalConfirmed}, from: models.RentalConfirmed, to: models.RentalConfirmed},
+		{name: "unknown status", from: models.RentalRequested, to: "lost"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rental := newRental(t, tt.path...)
+			before, err := repos.Rentals.Events(ctx, rental.ID)
+			if err != nil {
+				t.Fatalf("failed to list events: %v", err)
+			}
+
+			_, err = repos.Rentals.Transition(ctx, int(rental.ID), tt.to, nil, "")
+			var invalid *models.InvalidTransitionError
+			if !errors.As(err, &invalid) || !errors.Is(err, models.ErrInvalidTransition) {
+				t.Fatalf("expected an InvalidTransitionError, got %v", err)
+			}
+			if invalid.From != tt.from || invalid.To != tt.to {
+				t.Errorf("expected %s -> %s in the error, got %s -> %s", tt.from, tt.to, invalid.From, invalid.To)
+			}
+
+			stored, err := repos.Rentals.GetByID(ctx, int(rental.ID))
+			if err != nil {
+				t.Fatalf("failed to reload rental: %v", err)
+			}
+			if stored.Status != tt.from {
+				t.Errorf("expected status to stay %s, got %s", tt.from, stored.Status)
+			}
+			after, err := repos.Rentals.Events(ctx, rental.ID)
+			if err != nil {
+				t.Fatalf("failed to list events: %v", err)
+			}
+			if len(after) != len(before) {
+				t.Errorf("expected no event for a rejected move, got %d new", len(after)-len(before))
+			}
+		})
+	}
+}
+
+func TestTransitionConcurrentMovesApplyOnce(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+
+	machine := models.MesinBor{Name: "Metabo SBE 650", StockAvailability: 1, RentalCosts: 30000}
+	if err := repos.Machines.Create(ctx, &machine); err != nil {
+		t.Fatalf("failed to create machine: %v", err)
+	}
+	rental := models.RentalHistory{UserID: 1, MachineID: machine.ID, RentalDate: time.Now()}
+	if err := repos.Rentals.Create(ctx, &rental); err != nil {
+		t.Fatalf("failed to create rental: %v", err)
+	}
+
+	const clients = 10
+	var (
+		wg         sync.WaitGroup
+		mu         sync.Mutex
+		succeeded  int
+		rejected   int
+		unexpected []error
+	)
+	start := make(chan struct{})
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err := repos.Rentals.Transition(ctx, int(rental.ID), models.RentalCancelled, nil, "")
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+			case errors.Is(err, models.ErrInvalidTransition):
+				rejected++
+			default:
+				unexpected = append(unexpected, err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if len(unexpected) > 0 {
+		t.Fatalf("unexpected errors: %v", unexpected)
+	}
+	if succeeded != 1 || rejected != clients-1 {
+		t.Errorf("expected 1 cancellation and %d rejections, got %d and %d", clients-1, succeeded, rejected)
+	}
+
+	updated, err := repos.Machines.GetByID(ctx, int(machine.ID))
+	if err != nil {
+		t.Fatalf("failed to reload machine: %v", err)
+	}
+	if updated.StockAvailability != 1 {
+		t.Errorf("expected the unit to be released once, got stock %d", updated.StockAvailability)
+	}
+}
+
+// returnTestRental rents one unit of the machine to the user and returns it
+// straight away.
+func returnTestRental(t *testing.T, repos *Repositories, machineID, userID uint) *models.RentalHistory {
+	t.Helper()
+	ctx := context.Background()
+
+	rental := models.RentalHistory{UserID: userID, MachineID: machineID, RentalDate: time.Now().Add(-time.Hour)}
+	if err := repos.Rentals.Create(ctx, &rental); err != nil {
+		t.Fatalf("failed to create rental: %v", err)
+	}
+	for _, status := range []string{models.RentalConfirmed, models.RentalPickedUp} {
+		if _, err := repos.Rentals.Transition(ctx, int(rental.ID), status, nil, ""); err != nil {
+			t.Fatalf("failed to move rental to %s: %v", status, err)
+		}
+	}
+	returned, err := repos.Rentals.MarkAsReturned(ctx, int(rental.ID), time.Now(), nil, nil)
+	if err != nil {
+		t.Fatalf("failed to return rental: %v", err)
+	}
+	return returned
+}
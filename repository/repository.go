@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"rental-api/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when a record does not exist. It is the same value
+// as gorm.ErrRecordNotFound so either can be matched with errors.Is.
+var ErrNotFound = gorm.ErrRecordNotFound
+
+// ErrInvalidSort is returned when a listing is asked to sort on a field it
+// does not support.
+var ErrInvalidSort = errors.New("unsupported sort field")
+
+type UserRepository interface {
+	Create(ctx context.Context, user *models.User) error
+	GetByID(ctx context.Context, id int) (*models.User, error)
+	Update(ctx context.Context, id int, user *models.User) error
+	UpdateRole(ctx context.Context, id int, role string) error
+	UpdateRoleByEmail(ctx context.Context, email, role string) error
+	Delete(ctx context.Context, id int) error
+	ExistsByEmail(ctx context.Context, email string) (bool, error)
+	Authenticate(ctx context.Context, email, password string) (*models.User, error)
+	RehashPlaintextPasswords(ctx context.Context) (int, error)
+}
+
+type MachineRepository interface {
+	List(ctx context.Context, sort string) ([]models.MesinBor, error)
+	GetByID(ctx context.Context, id int) (*models.MesinBor, error)
+	Create(ctx context.Context, machine *models.MesinBor) error
+	Update(ctx context.Context, id int, machine *models.MesinBor) error
+	Delete(ctx context.Context, id int) error
+	Availability(ctx context.Context, id int, from, to time.Time) (*models.Availability, error)
+}
+
+type RentalRepository interface {
+	Create(ctx context.Context, rental *models.RentalHistory) error
+	GetByID(ctx context.Context, id int) (*models.RentalHistory, error)
+	List(ctx context.Context, filter RentalFilter, page Page) ([]models.RentalHistory, *PageInfo, error)
+	Transition(ctx context.Context, id int, to string, actorID *uint, note string) (*models.RentalHistory, error)
+	MarkAsReturned(ctx context.Context, id int, returnDate time.Time, actorID *uint, inspection *models.ReturnInspection) (*models.RentalHistory, error)
+	Inspection(ctx context.Context, rentalID uint) (*models.ReturnInspection, error)
+	Events(ctx context.Context, rentalID uint) ([]models.RentalStatusEvent, error)
+	FlagOverdue(ctx context.Context, now time.Time) (int, error)
+	AccrueLateFees(ctx context.Context, now time.Time) (int, error)
+	ListOverdue(ctx context.Context) ([]models.RentalHistory, error)
+	Extend(ctx context.Context, id int, newDueDate time.Time, actorID *uint, note string) (*models.RentalHistory, *models.RentalExtension, error)
+	Extensions(ctx context.Context, rentalID uint) ([]models.RentalExtension, error)
+}
+
+type ReviewRepository interface {
+	Create(ctx context.Context, review *models.Review) error
+	GetByID(ctx context.Context, id int) (*models.Review, error)
+	List(ctx context.Context, filter ReviewFilter, page Page) ([]models.Review, *PageInfo, error)
+	ListPending(ctx context.Context) ([]models.Review, error)
+	Update(ctx context.Context, id int, review *models.Review, blocked []string) error
+	Moderate(ctx context.Context, id int, status string, actorID *uint, reason string) (*models.Review, error)
+	Report(ctx context.Context, report *models.ReviewReport) error
+	SaveReply(ctx context.Context, reviewID int, authorID uint, body string) (*models.ReviewReply, error)
+	Delete(ctx context.Context, id int) error
+}
+
+type MaintenanceRepository interface {
+	Create(ctx context.Context, maintenance *models.Maintenance) error
+	GetByID(ctx context.Context, id int) (*models.Maintenance, error)
+	List(ctx context.Context, filter MaintenanceFilter, page Page) ([]models.Maintenance, *PageInfo, error)
+}
+
+type ReservationRepository interface {
+	Create(ctx context.Context, reservation *models.Reservation) error
+	GetByID(ctx context.Context, id int) (*models.Reservation, error)
+	List(ctx context.Context) ([]models.Reservation, error)
+	ListByUser(ctx context.Context, userID uint) ([]models.Reservation, error)
+	Cancel(ctx context.Context, id int) error
+	Fulfill(ctx context.Context, id int) (*models.RentalHistory, error)
+	Bookings(ctx context.Context, machineID uint, start, end time.Time) ([]models.Booking, error)
+}
+
+type LateFeePolicyRepository interface {
+	List(ctx context.Context) ([]models.LateFeePolicy, error)
+	Save(ctx context.Context, policy *models.LateFeePolicy) error
+	Delete(ctx context.Context, id int) error
+}
+
+type DepositRepository interface {
+	Balance(ctx context.Context, userID uint) (*models.DepositBalance, error)
+	Entries(ctx context.Context, userID uint) ([]models.DepositEntry, error)
+	Credit(ctx context.Context, userID uint, amount float64, reason string, actorID *uint) (*models.DepositEntry, error)
+	Refund(ctx context.Context, userID uint, amount float64, reason string, actorID *uint) (*models.DepositEntry, error)
+}
+
+type OrderRepository interface {
+	Create(ctx context.Context, order *models.RentalOrder) error
+	GetByID(ctx context.Context, id int) (*models.RentalOrder, error)
+	List(ctx context.Context) ([]models.RentalOrder, error)
+	ListByUser(ctx context.Context, userID uint) ([]models.RentalOrder, error)
+	Transition(ctx context.Context, id int, to string, actorID *uint, note string) (*models.RentalOrder, error)
+	Return(ctx context.Context, id int, returnDate time.Time, actorID *uint) (*models.RentalOrder, error)
+	ReturnLine(ctx context.Context, id int, lineID uint, quantity int, returnDate time.Time, actorID *uint) (*models.RentalOrder, error)
+}
+
+type InvoiceRepository interface {
+	GenerateForRental(ctx context.Context, rentalID uint) (*models.Invoice, bool, error)
+	GenerateForOrder(ctx context.Context, orderID uint) (*models.Invoice, bool, error)
+	GetByID(ctx context.Context, id int) (*models.Invoice, error)
+	List(ctx context.Context) ([]models.Invoice, error)
+	ListByUser(ctx context.Context, userID uint) ([]models.Invoice, error)
+	UpdateStatus(ctx context.Context, id int, status string) (*models.Invoice, error)
+}
+
+type PaymentRepository interface {
+	Create(ctx context.Context, payment *models.Payment) error
+	SetStatus(ctx context.Context, id uint, status string, externalID string) (*models.Payment, error)
+	GetByID(ctx context.Context, id int) (*models.Payment, error)
+	ListByInvoice(ctx context.Context, invoiceID uint) ([]models.Payment, error)
+	Balance(ctx context.Context, userID uint) (*models.AccountBalance, error)
+	ProcessWebhook(ctx context.Context, event *models.WebhookEvent) (bool, error)
+}
+
+type NotificationRepository interface {
+	ListByUser(ctx context.Context, userID uint, unreadOnly bool) ([]models.Notification, error)
+	MarkRead(ctx context.Context, id int, userID uint) (*models.Notification, error)
+}
+
+// Transactor runs fn inside a database transaction. Repository calls made with
+// the context passed to fn join that transaction.
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type Repositories struct {
+	Transactor
+	Users         UserRepository
+	Machines      MachineRepository
+	Rentals       RentalRepository
+	Reviews       ReviewRepository
+	Maintenance   MaintenanceRepository
+	Reservations  ReservationRepository
+	LateFees      LateFeePolicyRepository
+	Deposits      DepositRepository
+	Orders        OrderRepository
+	Invoices      InvoiceRepository
+	Payments      PaymentRepository
+	Notifications NotificationRepository
+}
+
+func New(db *gorm.DB) *Repositories {
+	return &Repositories{
+		Transactor:    &gormTransactor{db: db},
+		Users:         &gormUserRepository{db: db},
+		Machines:      &gormMachineRepository{db: db},
+		Rentals:       &gormRentalRepository{db: db},
+		Reviews:       &gormReviewRepository{db: db},
+		Maintenance:   &gormMaintenanceRepository{db: db},
+		Reservations:  &gormReservationRepository{db: db},
+		LateFees:      &gormLateFeePolicyRepository{db: db},
+		Deposits:      &gormDepositRepository{db: db},
+		Orders:        &gormOrderRepository{db: db},
+		Invoices:      &gormInvoiceRepository{db: db},
+		Payments:      &gormPaymentRepository{db: db},
+		Notifications: &gormNotificationRepository{db: db},
+	}
+}
+
+type txKey struct{}
+
+type gormTransactor struct {
+	db *gorm.DB
+}
+
+func (t *gormTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+	return t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// conn returns the transaction carried by ctx, or db bound to ctx when there
+// is none.
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx
+	}
+	return db.WithContext(ctx)
+}
+
+// transaction runs fn in the transaction carried by ctx, or starts a new one.
+func transaction(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return fn(tx)
+	}
+	return db.WithContext(ctx).Transaction(fn)
+}
@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"rental-api/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type gormReservationRepository struct {
+	db *gorm.DB
+}
+
+// Create books a unit of the machine for the reservation period. It fails with
+// a *models.BookingConflictError when every unit is already taken or in
+// maintenance for part of that period.
+func (r *gormReservationRepository) Create(ctx context.Context, reservation *models.Reservation) error {
+	if !reservation.EndDate.After(reservation.StartDate) {
+		return models.ErrInvalidBookingRange
+	}
+
+	return transaction(ctx, r.db, func(tx *gorm.DB) error {
+		machine, err := lockMachine(tx, reservation.MachineID)
+		if err != nil {
+			return err
+		}
+
+		if err := checkCapacity(tx, machine, reservation.StartDate, reservation.EndDate, nil); err != nil {
+			return err
+		}
+
+		reservation.Status = models.ReservationBooked
+		return tx.Create(reservation).Error
+	})
+}
+
+func (r *gormReservationRepository) GetByID(ctx context.Context, id int) (*models.Reservation, error) {
+	var reservation models.Reservation
+	if err := conn(ctx, r.db).First(&reservation, id).Error; err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+func (r *gormReservationRepository) List(ctx context.Context) ([]models.Reservation, error) {
+	var reservations []models.Reservation
+	if err := conn(ctx, r.db).Order("start_date").Find(&reservations).Error; err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+func (r *gormReservationRepository) ListByUser(ctx context.Context, userID uint) ([]models.Reservation, error) {
+	var reservations []models.Reservation
+	if err := conn(ctx, r.db).Where("user_id = ?", userID).Order("start_date").Find(&reservations).Error; err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+func (r *gormReservationRepository) Cancel(ctx context.Context, id int) error {
+	db := conn(ctx, r.db)
+
+	result := db.Model(&models.Reservation{}).
+		Where("id = ? AND status = ?", id, models.ReservationBooked).
+		Update("status", models.ReservationCancelled)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := r.GetByID(ctx, id); err != nil {
+			return err
+		}
+		return models.ErrReservationNotOpen
+	}
+	return nil
+}
+
+// Fulfill turns a booked reservation into a rental for the reserved period
+// and marks the reservation fulfilled, so the unit is not counted twice.
+func (r *gormReservationRepository) Fulfill(ctx context.Context, id int) (*models.RentalHistory, error) {
+	var rental models.RentalHistory
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		var reservation models.Reservation
+		if err := tx.First(&reservation, id).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&reservation).
+			Where("status = ?", models.ReservationBooked).
+			Update("status", models.ReservationFulfilled)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return models.ErrReservationNotOpen
+		}
+
+		endDate := reservation.EndDate
+		rental = models.RentalHistory{
+			UserID:     reservation.UserID,
+			MachineID:  reservation.MachineID,
+			RentalDate: reservation.StartDate,
+			DueDate:    &endDate,
+		}
+		return createRental(tx, &rental, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rental, nil
+}
+
+func (r *gormReservationRepository) Bookings(ctx context.Context, machineID uint, start, end time.Time) ([]models.Booking, error) {
+	return overlappingBookings(conn(ctx, r.db), machineID, start, end)
+}
+
+// lockMachine takes a row lock on the machine for the rest of the transaction
+// so that concurrent bookings for it are checked one at a time.
+func lockMachine(tx *gorm.DB, id uint) (*models.MesinBor, error) {
+	if err := tx.Model(&models.MesinBor{}).Where("id = ?", id).
+		UpdateColumn("updated_at", gorm.Expr("updated_at")).Error; err != nil {
+		return nil, err
+	}
+
+	var machine models.MesinBor
+	if err := tx.First(&machine, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrMachineNotFound
+		}
+		return nil, err
+	}
+	return &machine, nil
+}
+
+// machineCapacity is the number of units the machine has in total: those on
+// the shelf plus those currently out on rental.
+func machineCapacity(tx *gorm.DB, machine *models.MesinBor) (int, error) {
+	var rented int64
+	if err := tx.Model(&models.RentalHistory{}).
+		Scopes(openRentals).
+		Where("machine_id = ?", machine.ID).
+		Count(&rented).Error; err != nil {
+		return 0, err
+	}
+	return machine.StockAvailability + int(rented), nil
+}
+
+// checkCapacity makes sure one more unit of the machine is free for the whole
+// of [start, end): the bookings active at the same moment plus the units in
+// maintenance at any point of the period must leave one over, which is how
+// the availability calendar counts them too. skip leaves out the booking
+// being changed. Call it with the machine locked.
+func checkCapacity(tx *gorm.DB, machine *models.MesinBor, start, end time.Time, skip *models.Booking) error {
+	capacity, err := machineCapacity(tx, machine)
+	if err != nil {
+		return err
+	}
+
+	bookings, err := overlappingBookings(tx, machine.ID, start, end)
+	if err != nil {
+		return err
+	}
+	if skip != nil {
+		others := bookings[:0]
+		for _, booking := range bookings {
+			if booking.Type != skip.Type || booking.ID != skip.ID {
+				others = append(others, booking)
+			}
+		}
+		bookings = others
+	}
+
+	maintenance, err := activeMaintenance(tx, machine.ID, start, end)
+	if err != nil {
+		return err
+	}
+
+	if models.PeakOverlap(bookings, start, end)+len(maintenance)+1 > capacity {
+		return &models.BookingConflictError{Capacity: capacity, Maintenance: len(maintenance), Conflicts: bookings}
+	}
+	return nil
+}
+
+// activeMaintenance returns the machine's maintenance records that keep a
+// unit out of service at some point within [start, end).
+func activeMaintenance(tx *gorm.DB, machineID uint, start, end time.Time) ([]models.Maintenance, error) {
+	var maintenance []models.Maintenance
+	if err := tx.Where("machine_id = ? AND created_at < ? AND (fixed = ? OR fixed_at > ?)",
+		machineID, end, false, start).Find(&maintenance).Error; err != nil {
+		return nil, err
+	}
+	return maintenance, nil
+}
+
+// overlappingBookings returns the reservations and open rentals of the machine
+// that overlap [start, end). An open rental past its due date keeps its unit
+// until it is returned, so it is treated as having no end.
+func overlappingBookings(tx *gorm.DB, machineID uint, start, end time.Time) ([]models.Booking, error) {
+	now := time.Now()
+
+	var reservations []models.Reservation
+	if err := tx.Where("machine_id = ? AND status = ? AND start_date < ? AND end_date > ?",
+		machineID, models.ReservationBooked, end, start).
+		Order("start_date").Find(&reservations).Error; err != nil {
+		return nil, err
+	}
+
+	var rentals []models.RentalHistory
+	if err := tx.Scopes(openRentals).
+		Where("machine_id = ? AND rental_date < ? AND (due_date IS NULL OR due_date > ? OR due_date <= ?)", machineID, end, start, now).
+		Order("rental_date").Find(&rentals).Error; err != nil {
+		return nil, err
+	}
+
+	bookings := make([]models.Booking, 0, len(reservations)+len(rentals))
+	for _, rental := range rentals {
+		endDate := rental.DueDate
+		if endDate != nil && !endDate.After(now) {
+			endDate = nil
+		}
+		bookings = append(bookings, models.Booking{
+			Type:      models.BookingRental,
+			ID:        rental.ID,
+			UserID:    rental.UserID,
+			StartDate: rental.RentalDate,
+			EndDate:   endDate,
+		})
+	}
+	for _, reservation := range reservations {
+		endDate := reservation.EndDate
+		bookings = append(bookings, models.Booking{
+			Type:      models.BookingReservation,
+			ID:        reservation.ID,
+			UserID:    reservation.UserID,
+			StartDate: reservation.StartDate,
+			EndDate:   &endDate,
+		})
+	}
+	return bookings, nil
+}
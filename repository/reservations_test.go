@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"rental-api/models"
+	"testing"
+	"time"
+)
+
+func createTestMachine(t *testing.T, repos *Repositories, name string, stock int) *models.MesinBor {
+	t.Helper()
+
+	machine := models.MesinBor{Name: name, StockAvailability: stock, RentalCosts: 50000}
+	if err := repos.Machines.Create(context.Background(), &machine); err != nil {
+		t.Fatalf("failed to create machine: %v", err)
+	}
+	return &machine
+}
+
+func TestCreateRentalRespectsReservations(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+	machine := createTestMachine(t, repos, "Bosch GBH 5-40", 1)
+
+	start := time.Now().Add(48 * time.Hour)
+	reservation := models.Reservation{UserID: 1, MachineID: machine.ID, StartDate: start, EndDate: start.Add(72 * time.Hour)}
+	if err := repos.Reservations.Create(ctx, &reservation); err != nil {
+		t.Fatalf("failed to create reservation: %v", err)
+	}
+
+	due := start.Add(24 * time.Hour)
+	err := repos.Rentals.Create(ctx, &models.RentalHistory{UserID: 2, MachineID: machine.ID, RentalDate: time.Now(), DueDate: &due})
+	var conflict *models.BookingConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected a booking conflict for a rental over the reservation, got %v", err)
+	}
+
+	err = repos.Rentals.Create(ctx, &models.RentalHistory{UserID: 2, MachineID: machine.ID, RentalDate: time.Now()})
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected a booking conflict for an open-ended rental, got %v", err)
+	}
+
+	before := start.Add(-time.Hour)
+	if err := repos.Rentals.Create(ctx, &models.RentalHistory{UserID: 2, MachineID: machine.ID, RentalDate: time.Now(), DueDate: &before}); err != nil {
+		t.Fatalf("expected a rental that ends before the reservation to succeed, got %v", err)
+	}
+}
+
+func TestFulfillReservation(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+	machine := createTestMachine(t, repos, "Makita HR4013C", 1)
+
+	start := time.Now().Add(24 * time.Hour)
+	end := start.Add(48 * time.Hour)
+	reservation := models.Reservation{UserID: 1, MachineID: machine.ID, StartDate: start, EndDate: end}
+	if err := repos.Reservations.Create(ctx, &reservation); err != nil {
+		t.Fatalf("failed to create reservation: %v", err)
+	}
+
+	rental, err := repos.Reservations.Fulfill(ctx, int(reservation.ID))
+	if err != nil {
+		t.Fatalf("failed to fulfill reservation: %v", err)
+	}
+	if rental.UserID != reservation.UserID || !rental.RentalDate.Equal(start) || rental.DueDate == nil || !rental.DueDate.Equal(end) {
+		t.Errorf("expected a rental over the reserved period, got %+v", rental)
+	}
+
+	stored, err := repos.Reservations.GetByID(ctx, int(reservation.ID))
+	if err != nil {
+		t.Fatalf("failed to reload reservation: %v", err)
+	}
+	if stored.Status != models.ReservationFulfilled {
+		t.Errorf("expected reservation to be fulfilled, got %s", stored.Status)
+	}
+
+	bookings, err := repos.Reservations.Bookings(ctx, machine.ID, start, end)
+	if err != nil {
+		t.Fatalf("failed to list bookings: %v", err)
+	}
+	if len(bookings) != 1 || bookings[0].Type != models.BookingRental {
+		t.Errorf("expected only the rental to be booked, got %+v", bookings)
+	}
+
+	if _, err := repos.Reservations.Fulfill(ctx, int(reservation.ID)); !errors.Is(err, models.ErrReservationNotOpen) {
+		t.Errorf("expected ErrReservationNotOpen on second fulfillment, got %v", err)
+	}
+}
+
+func TestCreateReservationCountsMaintenance(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+	machine := createTestMachine(t, repos, "Hilti TE 70", 2)
+
+	if err := repos.Maintenance.Create(ctx, &models.Maintenance{MachineID: machine.ID, Issue: "Chuck worn"}); err != nil {
+		t.Fatalf("failed to log maintenance: %v", err)
+	}
+
+	start := time.Now().Add(24 * time.Hour)
+	first := models.Reservation{UserID: 1, MachineID: machine.ID, StartDate: start, EndDate: start.Add(24 * time.Hour)}
+	if err := repos.Reservations.Create(ctx, &first); err != nil {
+		t.Fatalf("expected the unit not in maintenance to be bookable, got %v", err)
+	}
+
+	second := models.Reservation{UserID: 2, MachineID: machine.ID, StartDate: start, EndDate: start.Add(24 * time.Hour)}
+	err := repos.Reservations.Create(ctx, &second)
+	var conflict *models.BookingConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected a booking conflict, got %v", err)
+	}
+	if conflict.Maintenance != 1 || len(conflict.Conflicts) != 1 {
+		t.Errorf("expected 1 unit in maintenance and 1 conflict, got %d and %d", conflict.Maintenance, len(conflict.Conflicts))
+	}
+
+	availability, err := repos.Machines.Availability(ctx, int(machine.ID), start, start)
+	if err != nil {
+		t.Fatalf("failed to compute availability: %v", err)
+	}
+	if got := availability.Days[0].Available; got != 0 {
+		t.Errorf("expected the calendar to show no free unit, got %d", got)
+	}
+}
+
+func TestCreateReservationAtCapacity(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+	machine := createTestMachine(t, repos, "Hitachi DH 28PC", 2)
+
+	base := time.Now().Add(7 * 24 * time.Hour).Truncate(time.Hour)
+	day := func(n float64) time.Time {
+		return base.Add(time.Duration(n * 24 * float64(time.Hour)))
+	}
+	reserve := func(start, end float64) error {
+		return repos.Reservations.Create(ctx, &models.Reservation{UserID: 1, MachineID: machine.ID, StartDate: day(start), EndDate: day(end)})
+	}
+
+	for _, period := range [][2]float64{{1, 3}, {2, 4}} {
+		if err := reserve(period[0], period[1]); err != nil {
+			t.Fatalf("failed to reserve days %v-%v: %v", period[0], period[1], err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		start    float64
+		end      float64
+		conflict bool
+	}{
+		{name: "where both units are booked", start: 2, end: 3, conflict: true},
+		{name: "across the busy period", start: 0, end: 5, conflict: true},
+		{name: "ending as the first starts", start: 0, end: 1, conflict: false},
+		{name: "starting as the first ends", start: 3, end: 5, conflict: false},
+		{name: "touching both busy edges", start: 2.5, end: 3.5, conflict: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := reserve(tt.start, tt.end)
+			var conflict *models.BookingConflictError
+			if tt.conflict {
+				if !errors.As(err, &conflict) {
+					t.Fatalf("expected a BookingConflictError, got %v", err)
+				}
+				if conflict.Capacity != 2 {
+					t.Errorf("expected capacity 2, got %d", conflict.Capacity)
+				}
+			} else if err != nil {
+				t.Fatalf("expected the reservation to fit, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLateRentalBlocksReservation(t *testing.T) {
+	for _, flagged := range []bool{false, true} {
+		name := "picked up past due"
+		if flagged {
+			name = "flagged overdue"
+		}
+		t.Run(name, func(t *testing.T) {
+			repos := setupTestRepositories(t)
+			ctx := context.Background()
+			machine := createTestMachine(t, repos, "Milwaukee M18", 1)
+
+			due := time.Now().Add(-48 * time.Hour)
+			rental := models.RentalHistory{UserID: 1, MachineID: machine.ID, RentalDate: due.Add(-72 * time.Hour), DueDate: &due}
+			if err := repos.Rentals.Create(ctx, &rental); err != nil {
+				t.Fatalf("failed to create rental: %v", err)
+			}
+			for _, status := range []string{models.RentalConfirmed, models.RentalPickedUp} {
+				if _, err := repos.Rentals.Transition(ctx, int(rental.ID), status, nil, ""); err != nil {
+					t.Fatalf("failed to move rental to %s: %v", status, err)
+				}
+			}
+			if flagged {
+				if n, err := repos.Rentals.FlagOverdue(ctx, time.Now()); err != nil || n != 1 {
+					t.Fatalf("failed to flag rental overdue: flagged %d, %v", n, err)
+				}
+			}
+
+			start := time.Now().Add(7 * 24 * time.Hour)
+			err := repos.Reservations.Create(ctx, &models.Reservation{UserID: 2, MachineID: machine.ID, StartDate: start, EndDate: start.Add(24 * time.Hour)})
+			var conflict *models.BookingConflictError
+			if !errors.As(err, &conflict) {
+				t.Fatalf("expected the late rental to block the reservation, got %v", err)
+			}
+			if len(conflict.Conflicts) != 1 || conflict.Conflicts[0].ID != rental.ID || conflict.Conflicts[0].EndDate != nil {
+				t.Errorf("expected the late rental as an open-ended conflict, got %+v", conflict.Conflicts)
+			}
+
+			availability, err := repos.Machines.Availability(ctx, int(machine.ID), start, start)
+			if err != nil {
+				t.Fatalf("failed to get availability: %v", err)
+			}
+			if day := availability.Days[0]; day.Booked != 1 || day.Available != 0 {
+				t.Errorf("expected the unit booked on the calendar, got %+v", day)
+			}
+		})
+	}
+}
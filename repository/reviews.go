@@ -0,0 +1,365 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rental-api/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type gormReviewRepository struct {
+	db *gorm.DB
+}
+
+// Create stores a review of the rental in review.RentalID. The rental must
+// belong to the reviewer and have been returned, and each rental can be
+// reviewed once. The machine is taken from the rental. Only approved reviews
+// count towards the machine's rating.
+func (r *gormReviewRepository) Create(ctx context.Context, review *models.Review) error {
+	if err := review.Validate(); err != nil {
+		return err
+	}
+	if review.RentalID == nil {
+		return models.ErrReviewNotAllowed
+	}
+	if review.Status == "" {
+		review.Status = models.ReviewPending
+	}
+
+	return transaction(ctx, r.db, func(tx *gorm.DB) error {
+		var rental models.RentalHistory
+		if err := tx.First(&rental, *review.RentalID).Error; err != nil {
+			return err
+		}
+		if rental.UserID != review.UserID || rental.Status != models.RentalReturned {
+			return models.ErrReviewNotAllowed
+		}
+
+		var existing int64
+		if err := tx.Model(&models.Review{}).Where("rental_id = ?", rental.ID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return models.ErrAlreadyReviewed
+		}
+
+		review.MachineID = rental.MachineID
+		if err := tx.Create(review).Error; err != nil {
+			return err
+		}
+		return rerateReview(tx, review.MachineID, nil, review)
+	})
+}
+
+func (r *gormReviewRepository) GetByID(ctx context.Context, id int) (*models.Review, error) {
+	var review models.Review
+	if err := conn(ctx, r.db).Preload("Reply").First(&review, id).Error; err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+var reviewSorts = sortFields{
+	fallback: "-created_at",
+	columns: map[string]string{
+		"id":         "id",
+		"created_at": "created_at",
+		"rating":     "rating",
+	},
+}
+
+// List returns a page of the published reviews matching filter.
+func (r *gormReviewRepository) List(ctx context.Context, filter ReviewFilter, page Page) ([]models.Review, *PageInfo, error) {
+	query := conn(ctx, r.db).Model(&models.Review{}).Where("status = ?", models.ReviewApproved)
+	if filter.MachineID != nil {
+		query = query.Where("machine_id = ?", *filter.MachineID)
+	}
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.MinRating != nil {
+		query = query.Where("rating >= ?", *filter.MinRating)
+	}
+	if filter.MaxRating != nil {
+		query = query.Where("rating <= ?", *filter.MaxRating)
+	}
+	query = filter.Created.apply(query, "created_at")
+
+	var reviews []models.Review
+	info, err := paginate(query, page, reviewSorts, &reviews, "Reply")
+	if err != nil {
+		return nil, nil, err
+	}
+	return reviews, info, nil
+}
+
+// ListPending returns the moderation queue, oldest first, with each review's
+// open reports.
+func (r *gormReviewRepository) ListPending(ctx context.Context) ([]models.Review, error) {
+	var reviews []models.Review
+	if err := conn(ctx, r.db).
+		Preload("Reports", "resolved = ?", false).
+		Where("status = ?", models.ReviewPending).
+		Order("updated_at, id").
+		Find(&reviews).Error; err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// Update changes the rating and comment of a review. The new text is screened
+// with blocked, the terms the caller's filter found in it, and the machine's
+// rating follows any change in rating or status.
+func (r *gormReviewRepository) Update(ctx context.Context, id int, updated *models.Review, blocked []string) error {
+	if err := updated.Validate(); err != nil {
+		return err
+	}
+
+	return transaction(ctx, r.db, func(tx *gorm.DB) error {
+		review, err := lockReview(tx, id)
+		if err != nil {
+			return err
+		}
+		before := *review
+
+		review.Rating = updated.Rating
+		review.Comment = updated.Comment
+		review.Screen(blocked)
+
+		if err := tx.Model(review).Updates(map[string]interface{}{
+			"rating":            review.Rating,
+			"comment":           review.Comment,
+			"status":            review.Status,
+			"moderation_reason": review.ModerationReason,
+		}).Error; err != nil {
+			return err
+		}
+		*updated = *review
+
+		return rerateReview(tx, review.MachineID, &before, review)
+	})
+}
+
+// Moderate approves or rejects a review awaiting moderation and closes its
+// reports.
+func (r *gormReviewRepository) Moderate(ctx context.Context, id int, status string, actorID *uint, reason string) (*models.Review, error) {
+	var review *models.Review
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		var err error
+		review, err = lockReview(tx, id)
+		if err != nil {
+			return err
+		}
+		if review.Status != models.ReviewPending {
+			return models.ErrReviewNotPending
+		}
+		before := *review
+
+		now := time.Now()
+		review.Status = status
+		review.ModeratedBy = actorID
+		review.ModeratedAt = &now
+		if reason != "" || status == models.ReviewApproved {
+			review.ModerationReason = reason
+		}
+
+		if err := tx.Model(review).Updates(map[string]interface{}{
+			"status":            review.Status,
+			"moderated_by":      review.ModeratedBy,
+			"moderated_at":      review.ModeratedAt,
+			"moderation_reason": review.ModerationReason,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.ReviewReport{}).
+			Where("review_id = ? AND resolved = ?", review.ID, false).
+			Update("resolved", true).Error; err != nil {
+			return err
+		}
+
+		return rerateReview(tx, review.MachineID, &before, review)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+// Report records a user's complaint about someone else's published review.
+// The review goes back to moderation and leaves the machine's rating until
+// staff decide on it.
+func (r *gormReviewRepository) Report(ctx context.Context, report *models.ReviewReport) error {
+	return transaction(ctx, r.db, func(tx *gorm.DB) error {
+		review, err := lockReview(tx, int(report.ReviewID))
+		if err != nil {
+			return err
+		}
+		if review.UserID == report.ReporterID || !review.Published() {
+			return models.ErrReviewNotReportable
+		}
+
+		var existing int64
+		if err := tx.Model(&models.ReviewReport{}).
+			Where("review_id = ? AND reporter_id = ?", review.ID, report.ReporterID).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return models.ErrAlreadyReported
+		}
+
+		report.Resolved = false
+		if err := tx.Create(report).Error; err != nil {
+			return err
+		}
+
+		before := *review
+		review.Status = models.ReviewPending
+		review.ModerationReason = "Reported by users"
+		if err := tx.Model(review).Updates(map[string]interface{}{
+			"status":            review.Status,
+			"moderation_reason": review.ModerationReason,
+		}).Error; err != nil {
+			return err
+		}
+		return rerateReview(tx, review.MachineID, &before, review)
+	})
+}
+
+// SaveReply writes the staff reply to a published review, or edits the one it
+// has, and lets the reviewer know.
+func (r *gormReviewRepository) SaveReply(ctx context.Context, reviewID int, authorID uint, body string) (*models.ReviewReply, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, models.ErrEmptyReply
+	}
+
+	var reply models.ReviewReply
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		review, err := lockReview(tx, reviewID)
+		if err != nil {
+			return err
+		}
+		if !review.Published() {
+			return models.ErrReviewNotPublished
+		}
+
+		kind := models.NotificationReviewReplyEdited
+		message := fmt.Sprintf("The reply to your review #%d was updated.", review.ID)
+		err = tx.Where("review_id = ?", review.ID).First(&reply).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			reply = models.ReviewReply{ReviewID: review.ID, AuthorID: authorID, Body: body}
+			if err := tx.Create(&reply).Error; err != nil {
+				return err
+			}
+			kind = models.NotificationReviewReply
+			message = fmt.Sprintf("Our team replied to your review #%d.", review.ID)
+		case err != nil:
+			return err
+		default:
+			if err := tx.Model(&reply).Updates(map[string]interface{}{"author_id": authorID, "body": body}).Error; err != nil {
+				return err
+			}
+		}
+
+		return notify(tx, &models.Notification{
+			UserID:       review.UserID,
+			Kind:         kind,
+			Message:      message,
+			ResourceType: "review",
+			ResourceID:   &review.ID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &reply, nil
+}
+
+func (r *gormReviewRepository) Delete(ctx context.Context, id int) error {
+	return transaction(ctx, r.db, func(tx *gorm.DB) error {
+		review, err := lockReview(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("review_id = ?", review.ID).Delete(&models.ReviewReport{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("review_id = ?", review.ID).Delete(&models.ReviewReply{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(review).Error; err != nil {
+			return err
+		}
+		return rerateReview(tx, review.MachineID, review, nil)
+	})
+}
+
+// lockReview loads the review after touching its row, so changes to it are
+// serialized until the transaction ends.
+func lockReview(tx *gorm.DB, id int) (*models.Review, error) {
+	result := tx.Model(&models.Review{}).Where("id = ?", id).
+		UpdateColumn("updated_at", gorm.Expr("updated_at"))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+
+	var review models.Review
+	if err := tx.First(&review, id).Error; err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+// rerateReview moves a review's contribution to the machine's rating from
+// before to after. Either may be nil, and unpublished reviews count for
+// nothing.
+func rerateReview(tx *gorm.DB, machineID uint, before, after *models.Review) error {
+	counted := func(review *models.Review) bool { return review != nil && review.Published() }
+	if !counted(before) && !counted(after) {
+		return nil
+	}
+	if counted(before) && counted(after) && before.Rating == after.Rating {
+		return nil
+	}
+
+	return updateMachineRating(tx, machineID, func(summary *models.RatingSummary) {
+		if counted(before) {
+			summary.Add(before.Rating, -1)
+		}
+		if counted(after) {
+			summary.Add(after.Rating, 1)
+		}
+	})
+}
+
+// updateMachineRating applies change to the machine's rating aggregates. The
+// row is locked first so concurrent reviews do not lose each other's counts.
+// Deleted machines are included, as their reviews can still change.
+func updateMachineRating(tx *gorm.DB, machineID uint, change func(summary *models.RatingSummary)) error {
+	result := tx.Unscoped().Model(&models.MesinBor{}).Where("id = ?", machineID).
+		UpdateColumn("updated_at", gorm.Expr("updated_at"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrMachineNotFound
+	}
+
+	var machine models.MesinBor
+	if err := tx.Unscoped().First(&machine, machineID).Error; err != nil {
+		return err
+	}
+
+	change(&machine.Ratings)
+	return tx.Unscoped().Model(&machine).Select(models.RatingColumns).UpdateColumns(&machine).Error
+}
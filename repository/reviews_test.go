@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"rental-api/models"
+	"testing"
+	"time"
+)
+
+func checkRatings(t *testing.T, repos *Repositories, machineID uint, count, sum int) {
+	t.Helper()
+
+	machine, err := repos.Machines.GetByID(context.Background(), int(machineID))
+	if err != nil {
+		t.Fatalf("failed to reload machine: %v", err)
+	}
+	ratings := machine.Ratings
+	if ratings.Count != count || ratings.Sum != sum {
+		t.Fatalf("expected %d reviews summing to %d, got %d summing to %d", count, sum, ratings.Count, ratings.Sum)
+	}
+	if score := models.BayesianScore(sum, count); ratings.Score != score {
+		t.Errorf("expected score %v, got %v", score, ratings.Score)
+	}
+}
+
+func TestReviewRatingAggregates(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+	machine := createTestMachine(t, repos, "Bosch GBH 2-28", 4)
+
+	submit := func(userID uint, rating int, blocked ...string) *models.Review {
+		rental := returnTestRental(t, repos, machine.ID, userID)
+		review := models.Review{UserID: userID, RentalID: &rental.ID, Rating: rating}
+		review.Screen(blocked)
+		if err := repos.Reviews.Create(ctx, &review); err != nil {
+			t.Fatalf("failed to create review: %v", err)
+		}
+		return &review
+	}
+	moderate := func(review *models.Review, status string) {
+		if _, err := repos.Reviews.Moderate(ctx, int(review.ID), status, nil, ""); err != nil {
+			t.Fatalf("failed to moderate review: %v", err)
+		}
+	}
+
+	checkRatings(t, repos, machine.ID, 0, 0)
+
+	clean := submit(1, 5)
+	checkRatings(t, repos, machine.ID, 1, 5)
+
+	held := submit(2, 3, "scam")
+	checkRatings(t, repos, machine.ID, 1, 5)
+
+	moderate(held, models.ReviewApproved)
+	checkRatings(t, repos, machine.ID, 2, 8)
+
+	rejected := submit(3, 1, "scam")
+	moderate(rejected, models.ReviewRejected)
+	checkRatings(t, repos, machine.ID, 2, 8)
+
+	if err := repos.Reviews.Update(ctx, int(clean.ID), &models.Review{Rating: 4}, nil); err != nil {
+		t.Fatalf("failed to update review: %v", err)
+	}
+	checkRatings(t, repos, machine.ID, 2, 7)
+
+	if err := repos.Reviews.Report(ctx, &models.ReviewReport{ReviewID: held.ID, ReporterID: 3, Reason: "Spam"}); err != nil {
+		t.Fatalf("failed to report review: %v", err)
+	}
+	checkRatings(t, repos, machine.ID, 1, 4)
+
+	moderate(held, models.ReviewApproved)
+	checkRatings(t, repos, machine.ID, 2, 7)
+
+	if err := repos.Reviews.Delete(ctx, int(clean.ID)); err != nil {
+		t.Fatalf("failed to delete review: %v", err)
+	}
+	checkRatings(t, repos, machine.ID, 1, 3)
+
+	if err := repos.Reviews.Delete(ctx, int(rejected.ID)); err != nil {
+		t.Fatalf("failed to delete review: %v", err)
+	}
+	checkRatings(t, repos, machine.ID, 1, 3)
+
+	other := createTestMachine(t, repos, "Makita HR2630", 1)
+	rental := returnTestRental(t, repos, other.ID, 4)
+	review := models.Review{UserID: 4, RentalID: &rental.ID, Rating: 5}
+	review.Screen(nil)
+	if err := repos.Reviews.Create(ctx, &review); err != nil {
+		t.Fatalf("failed to create review: %v", err)
+	}
+
+	machines, err := repos.Machines.List(ctx, "score")
+	if err != nil {
+		t.Fatalf("failed to list machines: %v", err)
+	}
+	if len(machines) != 2 || machines[0].ID != other.ID {
+		t.Errorf("expected the machine with the better score first, got %+v", machines)
+	}
+}
+
+func TestCreateReviewEligibility(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+	machine := createTestMachine(t, repos, "Metabo KHA 18", 3)
+	other := createTestMachine(t, repos, "Metabo KHE 2660", 1)
+
+	returned := returnTestRental(t, repos, machine.ID, 1)
+
+	open := models.RentalHistory{UserID: 1, MachineID: machine.ID, RentalDate: time.Now()}
+	if err := repos.Rentals.Create(ctx, &open); err != nil {
+		t.Fatalf("failed to create rental: %v", err)
+	}
+	unknown := uint(999)
+
+	tests := []struct {
+		name     string
+		userID   uint
+		rentalID *uint
+		rating   int
+		err      error
+	}{
+		{name: "no rental", userID: 1, rentalID: nil, rating: 5, err: models.ErrReviewNotAllowed},
+		{name: "unknown rental", userID: 1, rentalID: &unknown, rating: 5, err: ErrNotFound},
+		{name: "someone else's rental", userID: 2, rentalID: &returned.ID, rating: 5, err: models.ErrReviewNotAllowed},
+		{name: "rental not returned", userID: 1, rentalID: &open.ID, rating: 5, err: models.ErrReviewNotAllowed},
+		{name: "rating too low", userID: 1, rentalID: &returned.ID, rating: 0, err: models.ErrInvalidRating},
+		{name: "rating too high", userID: 1, rentalID: &returned.ID, rating: 6, err: models.ErrInvalidRating},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			review := models.Review{UserID: tt.userID, RentalID: tt.rentalID, Rating: tt.rating}
+			if err := repos.Reviews.Create(ctx, &review); !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+
+	review := models.Review{UserID: 1, MachineID: other.ID, RentalID: &returned.ID, Rating: 4}
+	review.Screen(nil)
+	if err := repos.Reviews.Create(ctx, &review); err != nil {
+		t.Fatalf("failed to review own returned rental: %v", err)
+	}
+	if review.MachineID != machine.ID {
+		t.Errorf("expected the machine to come from the rental, got %d", review.MachineID)
+	}
+
+	again := models.Review{UserID: 1, RentalID: &returned.ID, Rating: 1}
+	if err := repos.Reviews.Create(ctx, &again); !errors.Is(err, models.ErrAlreadyReviewed) {
+		t.Errorf("expected ErrAlreadyReviewed for a second review, got %v", err)
+	}
+
+	reviews, info, err := repos.Reviews.List(ctx, ReviewFilter{}, Page{})
+	if err != nil {
+		t.Fatalf("failed to list reviews: %v", err)
+	}
+	if info.Total != 1 || len(reviews) != 1 || reviews[0].Rating != 4 {
+		t.Errorf("expected only the first review to be stored, got %d", info.Total)
+	}
+}
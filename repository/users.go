@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"rental-api/models"
+
+	"gorm.io/gorm"
+)
+
+type gormUserRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormUserRepository) Create(ctx context.Context, user *models.User) error {
+	hash, err := models.HashPassword(user.Password)
+	if err != nil {
+		return err
+	}
+	user.Password = hash
+
+	return conn(ctx, r.db).Create(user).Error
+}
+
+func (r *gormUserRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
+	var user models.User
+	if err := conn(ctx, r.db).First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) Update(ctx context.Context, id int, user *models.User) error {
+	db := conn(ctx, r.db)
+
+	var existingUser models.User
+	if err := db.First(&existingUser, id).Error; err != nil {
+		return err
+	}
+
+	existingUser.Email = user.Email
+	if user.Password != "" {
+		hash, err := models.HashPassword(user.Password)
+		if err != nil {
+			return err
+		}
+		existingUser.Password = hash
+	}
+	existingUser.FirstName = user.FirstName
+	existingUser.LastName = user.LastName
+
+	return db.Save(&existingUser).Error
+}
+
+func (r *gormUserRepository) UpdateRole(ctx context.Context, id int, role string) error {
+	return r.updateRole(ctx, role, "id = ?", id)
+}
+
+func (r *gormUserRepository) UpdateRoleByEmail(ctx context.Context, email, role string) error {
+	return r.updateRole(ctx, role, "email = ?", email)
+}
+
+func (r *gormUserRepository) updateRole(ctx context.Context, role string, query string, args ...interface{}) error {
+	if !models.IsValidRole(role) {
+		return models.ErrInvalidRole
+	}
+
+	result := conn(ctx, r.db).Model(&models.User{}).Where(query, args...).Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormUserRepository) Delete(ctx context.Context, id int) error {
+	return conn(ctx, r.db).Delete(&models.User{}, id).Error
+}
+
+func (r *gormUserRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
+	var user models.User
+	if err := conn(ctx, r.db).Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *gormUserRepository) Authenticate(ctx context.Context, email, password string) (*models.User, error) {
+	db := conn(ctx, r.db)
+
+	var user models.User
+	if err := db.Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			models.SimulatePasswordCheck(password)
+			return nil, models.ErrInvalidLogin
+		}
+		return nil, err
+	}
+
+	ok, needsUpgrade := models.CheckPassword(user.Password, password)
+	if !ok {
+		return nil, models.ErrInvalidLogin
+	}
+
+	if needsUpgrade {
+		hash, err := models.HashPassword(password)
+		if err != nil {
+			log.Println("Error hashing password during upgrade:", err)
+			return &user, nil
+		}
+		if err := db.Model(&user).Update("password", hash).Error; err != nil {
+			log.Println("Error upgrading plaintext password:", err)
+		}
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) RehashPlaintextPasswords(ctx context.Context) (int, error) {
+	db := conn(ctx, r.db)
+
+	var users []models.User
+	upgraded := 0
+
+	result := db.FindInBatches(&users, 100, func(tx *gorm.DB, batch int) error {
+		for _, user := range users {
+			if models.IsPasswordHash(user.Password) {
+				continue
+			}
+			hash, err := models.HashPassword(user.Password)
+			if err != nil {
+				return err
+			}
+			if err := db.Model(&models.User{}).Where("id = ? AND password = ?", user.ID, user.Password).
+				Update("password", hash).Error; err != nil {
+				return err
+			}
+			upgraded++
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return upgraded, result.Error
+	}
+	return upgraded, nil
+}
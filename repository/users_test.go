@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rental-api/models"
+	"testing"
+)
+
+// createLegacyUser stores a user with a plaintext password, the way accounts
+// were saved before passwords were hashed.
+func createLegacyUser(t *testing.T, repos *Repositories, email, password string) *models.User {
+	t.Helper()
+
+	user := models.User{Email: email, Password: password, Role: models.RoleCustomer}
+	if err := repos.Users.(*gormUserRepository).db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to create legacy user: %v", err)
+	}
+	return &user
+}
+
+func storedPassword(t *testing.T, repos *Repositories, id uint) string {
+	t.Helper()
+
+	user, err := repos.Users.GetByID(context.Background(), int(id))
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	return user.Password
+}
+
+func TestCreateUserHashesPassword(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+
+	user := models.User{Email: "new@example.com", Password: "s3cret", Role: models.RoleCustomer}
+	if err := repos.Users.Create(ctx, &user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if stored := storedPassword(t, repos, user.ID); !models.IsPasswordHash(stored) {
+		t.Fatalf("expected a hashed password to be stored, got %q", stored)
+	}
+
+	if _, err := repos.Users.Authenticate(ctx, "new@example.com", "s3cret"); err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+	if _, err := repos.Users.Authenticate(ctx, "new@example.com", "wrong"); !errors.Is(err, models.ErrInvalidLogin) {
+		t.Fatalf("expected ErrInvalidLogin for a wrong password, got %v", err)
+	}
+	if _, err := repos.Users.Authenticate(ctx, "nobody@example.com", "s3cret"); !errors.Is(err, models.ErrInvalidLogin) {
+		t.Fatalf("expected ErrInvalidLogin for an unknown email, got %v", err)
+	}
+}
+
+func TestAuthenticateUpgradesPlaintextPassword(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+
+	user := createLegacyUser(t, repos, "legacy@example.com", "password123")
+
+	if _, err := repos.Users.Authenticate(ctx, "legacy@example.com", "wrong"); !errors.Is(err, models.ErrInvalidLogin) {
+		t.Fatalf("expected ErrInvalidLogin for a wrong password, got %v", err)
+	}
+	if stored := storedPassword(t, repos, user.ID); stored != "password123" {
+		t.Fatalf("expected a failed login to leave the password alone, got %q", stored)
+	}
+
+	authenticated, err := repos.Users.Authenticate(ctx, "legacy@example.com", "password123")
+	if err != nil {
+		t.Fatalf("failed to authenticate legacy user: %v", err)
+	}
+	if authenticated.ID != user.ID {
+		t.Fatalf("expected user %d, got %d", user.ID, authenticated.ID)
+	}
+
+	stored := storedPassword(t, repos, user.ID)
+	if !models.IsPasswordHash(stored) {
+		t.Fatalf("expected the plaintext password to be replaced by a hash, got %q", stored)
+	}
+
+	if _, err := repos.Users.Authenticate(ctx, "legacy@example.com", "password123"); err != nil {
+		t.Fatalf("failed to authenticate after the upgrade: %v", err)
+	}
+	if again := storedPassword(t, repos, user.ID); again != stored {
+		t.Fatal("expected an upgraded password not to be rehashed on the next login")
+	}
+}
+
+func TestRehashPlaintextPasswords(t *testing.T) {
+	repos := setupTestRepositories(t)
+	ctx := context.Background()
+
+	passwords := make(map[uint]string)
+	for i := range 3 {
+		password := fmt.Sprintf("password%d", i)
+		user := createLegacyUser(t, repos, fmt.Sprintf("legacy%d@example.com", i), password)
+		passwords[user.ID] = password
+	}
+
+	hashed := models.User{Email: "hashed@example.com", Password: "s3cret", Role: models.RoleCustomer}
+	if err := repos.Users.Create(ctx, &hashed); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	hash := storedPassword(t, repos, hashed.ID)
+
+	upgraded, err := repos.Users.RehashPlaintextPasswords(ctx)
+	if err != nil {
+		t.Fatalf("failed to rehash passwords: %v", err)
+	}
+	if upgraded != len(passwords) {
+		t.Fatalf("expected %d passwords hashed, got %d", len(passwords), upgraded)
+	}
+
+	for id, password := range passwords {
+		stored := storedPassword(t, repos, id)
+		if !models.IsPasswordHash(stored) {
+			t.Fatalf("expected user %d to have a hashed password, got %q", id, stored)
+		}
+		if ok, _ := models.CheckPassword(stored, password); !ok {
+			t.Fatalf("expected user %d to keep their password", id)
+		}
+	}
+	if stored := storedPassword(t, repos, hashed.ID); stored != hash {
+		t.Fatal("expected an already hashed password to be left alone")
+	}
+
+	upgraded, err = repos.Users.RehashPlaintextPasswords(ctx)
+	if err != nil {
+		t.Fatalf("failed to rehash passwords again: %v", err)
+	}
+	if upgraded != 0 {
+		t.Fatalf("expected a second run to hash nothing, got %d", upgraded)
+	}
+}
@@ -3,38 +3,133 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"rental-api/controllers"
+	"rental-api/middleware"
+	"rental-api/models"
+	"rental-api/moderation"
+	"rental-api/payments"
+	"rental-api/repository"
 )
 
-func SetupRoutes(r *gin.Engine) {
-	maintenance := r.Group("/maintenance")
+func SetupRoutes(r *gin.Engine, repos *repository.Repositories, reviewFilter *moderation.Filter) {
+	h := controllers.NewHandler(repos, payments.DefaultRegistry(), reviewFilter)
+
+	auth := middleware.RequireAuth(repos.Users)
+	optionalAuth := middleware.OptionalAuth(repos.Users)
+	admin := middleware.RequireRole(models.RoleAdmin)
+	staff := middleware.RequireRole(models.RoleStaff)
+	technician := middleware.RequireRole(models.RoleTechnician)
+	crew := middleware.RequireRole(models.RoleStaff, models.RoleTechnician)
+
+	machines := r.Group("/machines")
+	{
+		machines.GET("/", h.ListMachines)
+		machines.GET("/:id", h.GetMachine)
+		machines.GET("/:id/availability", h.GetMachineAvailability)
+		machines.GET("/:id/reviews", h.ListMachineReviews)
+		machines.POST("/", auth, staff, h.CreateMachine)
+		machines.PUT("/:id", auth, staff, h.UpdateMachine)
+		machines.DELETE("/:id", auth, admin, h.DeleteMachine)
+	}
+
+	maintenance := r.Group("/maintenance", auth)
+	{
+		maintenance.POST("/", technician, h.LogMaintenance)
+		maintenance.GET("/:id", crew, h.GetMaintenance)
+		maintenance.GET("/", crew, h.ListMaintenance)
+	}
+
+	rentals := r.Group("/rentals", auth)
+	{
+		rentals.POST("/", h.CreateRental)
+		rentals.GET("/overdue", staff, h.ListOverdueRentals)
+		rentals.GET("/:id", h.GetRental)
+		rentals.GET("/", h.ListRentals)
+		rentals.PUT("/:id/confirm", staff, h.ConfirmRental)
+		rentals.PUT("/:id/pickup", staff, h.PickUpRental)
+		rentals.PUT("/:id/activate", staff, h.ActivateRental)
+		rentals.PUT("/:id/overdue", staff, h.MarkRentalOverdue)
+		rentals.PUT("/:id/return", staff, h.ReturnRental)
+		rentals.PUT("/:id/cancel", h.CancelRental)
+		rentals.POST("/:id/extend", h.ExtendRental)
+	}
+
+	orders := r.Group("/orders", auth)
 	{
-		maintenance.POST("/", controllers.LogMaintenance)
-		maintenance.GET("/:id", controllers.GetMaintenance)
-		maintenance.GET("/", controllers.ListMaintenance)
+		orders.POST("/", h.CreateOrder)
+		orders.GET("/:id", h.GetOrder)
+		orders.GET("/", h.ListOrders)
+		orders.PUT("/:id/confirm", staff, h.ConfirmOrder)
+		orders.PUT("/:id/pickup", staff, h.PickUpOrder)
+		orders.PUT("/:id/cancel", h.CancelOrder)
+		orders.PUT("/:id/return", staff, h.ReturnOrder)
+		orders.PUT("/:id/lines/:line_id/return", staff, h.ReturnOrderLine)
 	}
 
-	rentals := r.Group("/rentals")
+	invoices := r.Group("/invoices", auth)
 	{
-		rentals.POST("/", controllers.CreateRental)
-		rentals.GET("/:id", controllers.GetRental)
-		rentals.GET("/", controllers.ListRentals)
-		rentals.PUT("/:id/return", controllers.ReturnRental)
+		invoices.POST("/", staff, h.CreateInvoice)
+		invoices.GET("/:id", h.GetInvoice)
+		invoices.GET("/", h.ListInvoices)
+		invoices.PUT("/:id/void", staff, h.VoidInvoice)
+		invoices.GET("/:id/payments", h.ListInvoicePayments)
+		invoices.POST("/:id/payments", h.CreatePayment)
+		invoices.POST("/:id/refunds", staff, h.RefundPayment)
+	}
+
+	// Gateways call these without a user; the body signature authenticates
+	// them.
+	webhooks := r.Group("/payments/webhooks")
+	{
+		webhooks.POST("/:gateway", h.PaymentWebhook)
+	}
+
+	lateFees := r.Group("/late-fee-policies", auth)
+	{
+		lateFees.GET("/", staff, h.ListLateFeePolicies)
+		lateFees.POST("/", admin, h.SaveLateFeePolicy)
+		lateFees.DELETE("/:id", admin, h.DeleteLateFeePolicy)
+	}
+
+	reservations := r.Group("/reservations", auth)
+	{
+		reservations.POST("/", h.CreateReservation)
+		reservations.GET("/:id", h.GetReservation)
+		reservations.GET("/", h.ListReservations)
+		reservations.PUT("/:id/cancel", h.CancelReservation)
+		reservations.POST("/:id/rental", h.FulfillReservation)
 	}
 
 	reviews := r.Group("/reviews")
 	{
-		reviews.POST("/", controllers.SubmitReview)
-		reviews.GET("/:id", controllers.GetReview)
-		reviews.GET("/", controllers.ListReviews)
-		reviews.DELETE("/:id", controllers.DeleteReview)
+		reviews.POST("/", auth, h.SubmitReview)
+		reviews.GET("/moderation", auth, staff, h.ListPendingReviews)
+		reviews.GET("/:id", optionalAuth, h.GetReview)
+		reviews.GET("/", h.ListReviews)
+		reviews.PUT("/:id", auth, h.UpdateReview)
+		reviews.PUT("/:id/approve", auth, staff, h.ApproveReview)
+		reviews.PUT("/:id/reject", auth, staff, h.RejectReview)
+		reviews.POST("/:id/reports", auth, h.ReportReview)
+		reviews.PUT("/:id/reply", auth, staff, h.ReplyToReview)
+		reviews.DELETE("/:id", auth, h.DeleteReview)
+	}
+
+	notifications := r.Group("/notifications", auth)
+	{
+		notifications.GET("/", h.ListNotifications)
+		notifications.PUT("/:id/read", h.MarkNotificationRead)
 	}
 
 	users := r.Group("/users")
 	{
-		users.POST("/register", controllers.RegisterUser)
-		users.POST("/login", controllers.LoginUser)
-		users.GET("/:id", controllers.GetUser)
-		users.PUT("/:id", controllers.UpdateUser)
-		users.DELETE("/:id", controllers.DeleteUser)
+		users.POST("/register", h.RegisterUser)
+		users.POST("/login", h.LoginUser)
+		users.GET("/:id", auth, h.GetUser)
+		users.PUT("/:id", auth, h.UpdateUser)
+		users.DELETE("/:id", auth, h.DeleteUser)
+		users.PUT("/:id/role", auth, admin, h.UpdateUserRole)
+		users.GET("/:id/deposit", auth, h.GetUserDeposit)
+		users.GET("/:id/balance", auth, h.GetUserBalance)
+		users.POST("/:id/deposit/credit", auth, staff, h.CreditDeposit)
+		users.POST("/:id/deposit/refund", auth, staff, h.RefundDeposit)
 	}
 }
@@ -0,0 +1,123 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"rental-api/migrations"
+	"rental-api/models"
+	"rental-api/moderation"
+	"rental-api/repository"
+	"rental-api/utils"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupTestRouter(t *testing.T) (*gin.Engine, *repository.Repositories) {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_busy_timeout=10000&_journal_mode=WAL&_txlock=immediate"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	repos := repository.New(db)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	SetupRoutes(r, repos, moderation.NewFilter())
+	return r, repos
+}
+
+// TestRoleGuardedRoutes checks the role each guarded route asks for: other
+// roles get 403, admins get through every check and anonymous callers get
+// 401. Requests are sent without a body, so the ones let through fail in the
+// handler instead and change nothing.
+func TestRoleGuardedRoutes(t *testing.T) {
+	t.Setenv("JWT_SECRET_KEY", "test-secret")
+	r, repos := setupTestRouter(t)
+	ctx := context.Background()
+
+	tokens := make(map[string]string)
+	for _, role := range []string{models.RoleCustomer, models.RoleStaff, models.RoleTechnician, models.RoleAdmin} {
+		user := models.User{Email: role + "@example.com", Password: "secret", Role: role}
+		if err := repos.Users.Create(ctx, &user); err != nil {
+			t.Fatalf("failed to create %s: %v", role, err)
+		}
+		token, err := utils.GenerateJWT(&user)
+		if err != nil {
+			t.Fatalf("failed to generate token: %v", err)
+		}
+		tokens[role] = token
+	}
+
+	staffOnly := []string{models.RoleStaff}
+	adminOnly := []string{models.RoleAdmin}
+	technicianOnly := []string{models.RoleTechnician}
+	crew := []string{models.RoleStaff, models.RoleTechnician}
+
+	routes := []struct {
+		method string
+		path   string
+		roles  []string
+	}{
+		{http.MethodPost, "/machines/", staffOnly},
+		{http.MethodPut, "/machines/1", staffOnly},
+		{http.MethodDelete, "/machines/1", adminOnly},
+		{http.MethodPost, "/maintenance/", technicianOnly},
+		{http.MethodGet, "/maintenance/1", crew},
+		{http.MethodGet, "/rentals/overdue", staffOnly},
+		{http.MethodPut, "/rentals/1/return", staffOnly},
+		{http.MethodPut, "/orders/1/confirm", staffOnly},
+		{http.MethodPost, "/invoices/", staffOnly},
+		{http.MethodPut, "/invoices/1/void", staffOnly},
+		{http.MethodGet, "/late-fee-policies/", staffOnly},
+		{http.MethodPost, "/late-fee-policies/", adminOnly},
+		{http.MethodDelete, "/late-fee-policies/1", adminOnly},
+		{http.MethodGet, "/reviews/moderation", staffOnly},
+		{http.MethodPut, "/reviews/1/reply", staffOnly},
+		{http.MethodPut, "/users/1/role", adminOnly},
+		{http.MethodPost, "/users/1/deposit/credit", staffOnly},
+	}
+
+	for _, route := range routes {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(route.method, route.path, nil))
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("anonymous: expected status %d, got %d", http.StatusUnauthorized, w.Code)
+			}
+
+			for role, token := range tokens {
+				req := httptest.NewRequest(route.method, route.path, nil)
+				req.Header.Set("Authorization", "Bearer "+token)
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+
+				allowed := role == models.RoleAdmin || slices.Contains(route.roles, role)
+				switch {
+				case w.Code == http.StatusUnauthorized:
+					t.Errorf("%s: unexpected status %d: %s", role, w.Code, w.Body.String())
+				case allowed && w.Code == http.StatusForbidden:
+					t.Errorf("%s: expected to pass the role check, got %d: %s", role, w.Code, w.Body.String())
+				case !allowed && w.Code != http.StatusForbidden:
+					t.Errorf("%s: expected status %d, got %d", role, http.StatusForbidden, w.Code)
+				}
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
@@ -12,6 +13,11 @@ import (
 	"rental-api/models"
 )
 
+type Claims struct {
+	Role string `json:"role"`
+	jwt.StandardClaims
+}
+
 func RespondError(c *gin.Context, statusCode int, message string) {
 	c.JSON(statusCode, gin.H{
 		"status":  "error",
@@ -19,6 +25,14 @@ func RespondError(c *gin.Context, statusCode int, message string) {
 	})
 }
 
+func RespondErrorWithData(c *gin.Context, statusCode int, message string, data interface{}) {
+	c.JSON(statusCode, gin.H{
+		"status":  "error",
+		"message": message,
+		"data":    data,
+	})
+}
+
 func RespondJSON(c *gin.Context, statusCode int, data interface{}) {
 	c.JSON(statusCode, gin.H{
 		"status": "success",
@@ -26,10 +40,20 @@ func RespondJSON(c *gin.Context, statusCode int, data interface{}) {
 	})
 }
 
+// RespondPage is RespondJSON for one page of a listing; meta describes the
+// page.
+func RespondPage(c *gin.Context, statusCode int, data interface{}, meta interface{}) {
+	c.JSON(statusCode, gin.H{
+		"status": "success",
+		"data":   data,
+		"meta":   meta,
+	})
+}
+
 func GenerateJWT(user *models.User) (string, error) {
 	secretKey := []byte(os.Getenv("JWT_SECRET_KEY"))
 	if len(secretKey) == 0 {
-		log.Fatal("JWT_SECRET_KEY not set in environment variables")
+		log.Println("JWT_SECRET_KEY not set in environment variables")
 		return "", fmt.Errorf("secret key not set")
 	}
 
@@ -38,9 +62,12 @@ func GenerateJWT(user *models.User) (string, error) {
 		expirationDuration = 24 * time.Hour
 	}
 
-	claims := &jwt.StandardClaims{
-		Subject:   fmt.Sprintf("%d", user.ID),
-		ExpiresAt: time.Now().Add(expirationDuration).Unix(),
+	claims := &Claims{
+		Role: user.Role,
+		StandardClaims: jwt.StandardClaims{
+			Subject:   fmt.Sprintf("%d", user.ID),
+			ExpiresAt: time.Now().Add(expirationDuration).Unix(),
+		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -53,14 +80,17 @@ func GenerateJWT(user *models.User) (string, error) {
 	return tokenString, nil
 }
 
-func ParseJWT(tokenStr string) (*jwt.StandardClaims, error) {
+func ParseJWT(tokenStr string) (*Claims, error) {
 	secretKey := []byte(os.Getenv("JWT_SECRET_KEY"))
 	if len(secretKey) == 0 {
-		log.Fatal("JWT_SECRET_KEY not set in environment variables")
+		log.Println("JWT_SECRET_KEY not set in environment variables")
 		return nil, fmt.Errorf("secret key not set")
 	}
 
-	token, err := jwt.ParseWithClaims(tokenStr, &jwt.StandardClaims{}, func(token *jwt.Token) (interface{}, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
 		return secretKey, nil
 	})
 
@@ -68,7 +98,7 @@ func ParseJWT(tokenStr string) (*jwt.StandardClaims, error) {
 		return nil, fmt.Errorf("invalid or expired token: %v", err)
 	}
 
-	claims, ok := token.Claims.(*jwt.StandardClaims)
+	claims, ok := token.Claims.(*Claims)
 	if !ok {
 		return nil, fmt.Errorf("invalid claims")
 	}
@@ -77,11 +107,16 @@ func ParseJWT(tokenStr string) (*jwt.StandardClaims, error) {
 }
 
 func ExtractUserIDFromJWT(c *gin.Context) (uint, error) {
-	tokenString := c.GetHeader("Authorization")
-	if tokenString == "" {
+	header := c.GetHeader("Authorization")
+	if header == "" {
 		return 0, fmt.Errorf("token is missing in the Authorization header")
 	}
 
+	tokenString := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	if tokenString == header || tokenString == "" {
+		return 0, fmt.Errorf("authorization header must use the Bearer scheme")
+	}
+
 	claims, err := ParseJWT(tokenString)
 	if err != nil {
 		return 0, fmt.Errorf("failed to parse JWT: %v", err)